@@ -0,0 +1,404 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/schollz/urlss/client"
+	"github.com/schollz/urlss/urlss"
+)
+
+// commandUsage lists the commands that manage links
+const commandUsage = `Commands:
+  serve             run the server (default)
+  print-config      print the configuration in effect
+  shorten <url>     shorten a URL
+  resolve <code>    show the link of a code
+  list              list all links
+  delete <code>     delete a link and its stats
+  stats <code>      show the clicks of a link
+  import <file>     import links from a .csv, .json or .jsonl file,
+                    or JSONL on stdin for -, or the export of
+                    another shortener given by -from
+  export <file>     export all links to a .csv, .json or .jsonl file,
+                    or JSONL on stdout for -
+
+The commands change the data file, which is safe while a server
+runs on it, or the links of the server given by -server.
+`
+
+// linkStore is where the commands find links, the data
+// file or the API of a server
+type linkStore interface {
+	Shorten(ctx context.Context, rawURL string) (*client.Link, error)
+	Get(ctx context.Context, code string) (*client.Link, error)
+	List(ctx context.Context, offset, limit int) (*client.Page, error)
+	Delete(ctx context.Context, code string) error
+	Stats(ctx context.Context, code string) (*client.Stats, error)
+	Import(ctx context.Context, format string, r io.Reader, opts client.ImportOptions) (*client.ImportReport, error)
+	Export(ctx context.Context, format string, w io.Writer, opts client.ExportOptions) error
+}
+
+// localStore runs the commands on the data file of a server
+type localStore struct {
+	s *urlss.Server
+}
+
+func (l localStore) Shorten(ctx context.Context, rawURL string) (*client.Link, error) {
+	code, err := l.s.Shorten(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return l.Get(ctx, code)
+}
+
+func (l localStore) Get(ctx context.Context, code string) (*client.Link, error) {
+	link, err := l.s.Lookup(code)
+	if err != nil {
+		return nil, errors.New("Could not find " + code)
+	}
+	return newLink(code, link), nil
+}
+
+func (l localStore) List(ctx context.Context, offset, limit int) (*client.Page, error) {
+	codes := l.s.Codes()
+	page := &client.Page{Links: []client.Link{}, Offset: offset, Total: len(codes)}
+	for i := offset; i < len(codes) && len(page.Links) < limit; i++ {
+		if link, err := l.s.Lookup(codes[i]); err == nil {
+			page.Links = append(page.Links, *newLink(codes[i], link))
+		}
+	}
+	return page, nil
+}
+
+func (l localStore) Delete(ctx context.Context, code string) error {
+	return l.s.DeleteLink(code)
+}
+
+func (l localStore) Stats(ctx context.Context, code string) (*client.Stats, error) {
+	link, err := l.s.Lookup(code)
+	if err != nil {
+		return nil, errors.New("Could not find " + code)
+	}
+	stats := l.s.Stats(code)
+	answer := &client.Stats{Code: code, Params: link.Params}
+	convert(stats, &answer.Stats)
+	convert(link.VariantClicks(stats), &answer.Variants)
+	return answer, nil
+}
+
+func (l localStore) Import(ctx context.Context, format string, r io.Reader, opts client.ImportOptions) (*client.ImportReport, error) {
+	records, err := urlss.ReadRecords(r, format)
+	if err != nil {
+		return nil, err
+	}
+	report, err := l.s.Import(records, urlss.ImportOptions(opts))
+	var answer client.ImportReport
+	convert(report, &answer)
+	return &answer, err
+}
+
+func (l localStore) Export(ctx context.Context, format string, w io.Writer, opts client.ExportOptions) error {
+	return l.s.Export(w, format, urlss.ExportOptions(opts))
+}
+
+// newLink is a link as the API shows it, without its password hash
+func newLink(code string, l urlss.Link) *client.Link {
+	link := &client.Link{Code: code, Protected: l.PasswordHash != ""}
+	l.PasswordHash = ""
+	convert(l, &link.Options)
+	return link
+}
+
+// convert copies v into out, a type of the client with the same JSON
+func convert(v, out interface{}) {
+	data, err := json.Marshal(v)
+	if err == nil {
+		err = json.Unmarshal(data, out)
+	}
+	if err != nil {
+		panic(err)
+	}
+}
+
+// manageLinks runs a command on the links of the server at
+// the address server, or else on the data file of cfg
+func manageLinks(cfg urlss.Config, server string, opts commandOptions, args []string) error {
+	var links linkStore
+	if server != "" {
+		c := client.New(server)
+		c.Token = cfg.APIToken
+		links = c
+	} else {
+		s, err := urlss.NewServer(cfg, urlss.OpenStore(cfg.Data), nil)
+		if err != nil {
+			return err
+		}
+		links = localStore{s}
+	}
+	return runCommand(context.Background(), links, opts, args, os.Stdout)
+}
+
+// listPage is how many links list asks for at once
+const listPage = 1000
+
+// commandOptions are the flags of the commands
+type commandOptions struct {
+	// format is the output, table or json
+	format string
+	// from is the format of imported files, if not
+	// told by their extension
+	from    string
+	imports client.ImportOptions
+	exports client.ExportOptions
+}
+
+// runCommand runs the command in args on links, writing
+// its result to w as a table or as JSON
+func runCommand(ctx context.Context, links linkStore, opts commandOptions, args []string, w io.Writer) error {
+	if opts.format != "table" && opts.format != "json" {
+		return errors.New("unknown format " + opts.format + ", use table or json")
+	}
+	needs := map[string]int{"shorten": 1, "resolve": 1, "list": 0, "delete": 1, "stats": 1, "import": 1, "export": 1}
+	n, ok := needs[args[0]]
+	if !ok {
+		return errors.New("unknown command " + args[0])
+	}
+	if len(args) != n+1 {
+		return fmt.Errorf("%s takes %d argument(s)", args[0], n)
+	}
+	out := output{w, opts.format == "json"}
+	switch args[0] {
+	case "shorten", "resolve":
+		var l *client.Link
+		var err error
+		if args[0] == "shorten" {
+			l, err = links.Shorten(ctx, args[1])
+		} else {
+			l, err = links.Get(ctx, args[1])
+		}
+		if err != nil {
+			return err
+		}
+		if out.json {
+			return out.encode(l)
+		}
+		return out.links([]client.Link{*l})
+	case "list":
+		all := []client.Link{}
+		for {
+			page, err := links.List(ctx, len(all), listPage)
+			if err != nil {
+				return err
+			}
+			all = append(all, page.Links...)
+			if len(page.Links) == 0 || len(all) >= page.Total {
+				break
+			}
+		}
+		if out.json {
+			return out.encode(all)
+		}
+		return out.links(all)
+	case "delete":
+		if err := links.Delete(ctx, args[1]); err != nil {
+			return err
+		}
+		if out.json {
+			return out.encode(map[string]interface{}{"code": args[1], "deleted": true})
+		}
+		_, err := fmt.Fprintln(w, "Deleted", args[1])
+		return err
+	case "import":
+		report, err := importFile(ctx, links, args[1], opts.from, opts.imports)
+		if err != nil {
+			return err
+		}
+		if out.json {
+			return out.encode(report)
+		}
+		return out.report(report)
+	case "export":
+		if args[1] == "-" {
+			return links.Export(ctx, "jsonl", w, opts.exports)
+		}
+		if err := exportFile(ctx, links, args[1], opts.exports); err != nil {
+			return err
+		}
+		if out.json {
+			return out.encode(map[string]interface{}{"file": args[1], "exported": true})
+		}
+		_, err := fmt.Fprintln(w, "Exported to", args[1])
+		return err
+	default:
+		stats, err := links.Stats(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		if out.json {
+			return out.encode(stats)
+		}
+		return out.stats(stats)
+	}
+}
+
+// fileFormat is the format of the records in the file at path,
+// told by its extension. Standard input and output are JSONL,
+// and .sql files are YOURLS dumps.
+func fileFormat(path string) (string, error) {
+	if path == "-" {
+		return "jsonl", nil
+	}
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	if format == "sql" {
+		return "yourls", nil
+	}
+	for _, f := range urlss.Formats {
+		if f == format {
+			return format, nil
+		}
+	}
+	return "", errors.New("cannot tell the format of " + path + ", name it .csv, .json or .jsonl")
+}
+
+// importFile imports the records in the file at path, or on
+// standard input for -, which are in format if it is given
+func importFile(ctx context.Context, links linkStore, path, format string, opts client.ImportOptions) (*client.ImportReport, error) {
+	var err error
+	if format == "" {
+		if format, err = fileFormat(path); err != nil {
+			return nil, err
+		}
+	}
+	r := io.Reader(os.Stdin)
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	return links.Import(ctx, format, r, opts)
+}
+
+// exportFile writes every link to the file at path, which
+// is removed again if the export fails
+func exportFile(ctx context.Context, links linkStore, path string, opts client.ExportOptions) error {
+	format, err := fileFormat(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = links.Export(ctx, format, f, opts); err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+// output writes the results of commands
+type output struct {
+	w    io.Writer
+	json bool
+}
+
+func (o output) encode(v interface{}) error {
+	enc := json.NewEncoder(o.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func (o output) links(links []client.Link) error {
+	tw := tabwriter.NewWriter(o.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CODE\tURL\tOPTIONS")
+	for _, l := range links {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", l.Code, l.URL, linkOptions(l))
+	}
+	return tw.Flush()
+}
+
+func (o output) stats(stats *client.Stats) error {
+	tw := tabwriter.NewWriter(o.w, 0, 4, 2, ' ', 0)
+	last := "never"
+	if !stats.Stats.LastClick.IsZero() {
+		last = stats.Stats.LastClick.Local().Format(time.RFC3339)
+	}
+	fmt.Fprintln(tw, "CODE\tCLICKS\tLAST CLICK")
+	fmt.Fprintf(tw, "%s\t%d\t%s\n", stats.Code, stats.Stats.Clicks, last)
+	if len(stats.Variants) > 0 {
+		fmt.Fprintln(tw, "\nVARIANT\tCLICKS\tWEIGHT")
+		for _, v := range stats.Variants {
+			fmt.Fprintf(tw, "%s\t%d\t%d\n", v.URL, v.Clicks, v.Weight)
+		}
+	}
+	return tw.Flush()
+}
+
+func (o output) report(report *client.ImportReport) error {
+	tw := tabwriter.NewWriter(o.w, 0, 4, 2, ' ', 0)
+	if report.DryRun {
+		fmt.Fprintln(tw, "Dry run, nothing was changed")
+	}
+	fmt.Fprintln(tw, "CREATED\tOVERWRITTEN\tRENAMED\tSKIPPED\tFAILED")
+	fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%d\n", report.Created, report.Overwritten, len(report.Renamed), report.Skipped, len(report.Failed))
+	if len(report.Renamed) > 0 {
+		codes := make([]string, 0, len(report.Renamed))
+		for code := range report.Renamed {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		fmt.Fprintln(tw, "\nCODE\tRENAMED TO")
+		for _, code := range codes {
+			fmt.Fprintf(tw, "%s\t%s\n", code, report.Renamed[code])
+		}
+	}
+	if len(report.Failed) > 0 {
+		fmt.Fprintln(tw, "\nRECORD\tCODE\tERROR")
+		for _, f := range report.Failed {
+			fmt.Fprintf(tw, "%d\t%s\t%s\n", f.Record, f.Code, f.Error)
+		}
+	}
+	return tw.Flush()
+}
+
+// linkOptions sums up the options of a link for the table
+func linkOptions(l client.Link) string {
+	var options []string
+	for _, o := range []struct {
+		name string
+		set  bool
+	}{
+		{"prefix", l.Prefix},
+		{"params", len(l.Params) > 0},
+		{"rules", len(l.Rules) > 0},
+		{"schedule", len(l.Schedule) > 0},
+		{"variants", len(l.Variants) > 0},
+		{"protected", l.Protected},
+		{"signed", l.Signed},
+	} {
+		if o.set {
+			options = append(options, o.name)
+		}
+	}
+	if len(options) == 0 {
+		return "-"
+	}
+	return strings.Join(options, ",")
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/schollz/urlss/client"
+	"github.com/schollz/urlss/urlss"
+)
+
+func newTestServer(t *testing.T) *urlss.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	dir, err := ioutil.TempDir("", "urlss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	cfg := urlss.DefaultConfig()
+	cfg.Data = filepath.Join(dir, "urls.json.gz")
+	cfg.APIToken = "test-token"
+	s, err := urlss.NewServer(cfg, urlss.OpenStore(cfg.Data), urlss.NewLogger(ioutil.Discard, urlss.LogConfig{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestCommands(t *testing.T) {
+	s := newTestServer(t)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	for name, links := range map[string]linkStore{
+		"local":  localStore{s},
+		"remote": &client.Client{BaseURL: ts.URL, Token: "test-token"},
+	} {
+		run := func(format string, args ...string) (string, error) {
+			var buf bytes.Buffer
+			err := runCommand(context.Background(), links, commandOptions{format: format}, args, &buf)
+			return buf.String(), err
+		}
+		out, err := run("json", "shorten", "https://example.com/"+name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var l client.Link
+		if err = json.Unmarshal([]byte(out), &l); err != nil || l.URL != "https://example.com/"+name {
+			t.Fatalf("%s: got %s", name, out)
+		}
+
+		out, err = run("table", "resolve", l.Code)
+		if rows := strings.Split(out, "\n"); err != nil || len(rows) != 3 || strings.Join(strings.Fields(rows[1]), " ") != l.Code+" https://example.com/"+name+" -" {
+			t.Errorf("%s: got %q, %v", name, out, err)
+		}
+		out, err = run("table", "stats", l.Code)
+		if err != nil || !strings.Contains(out, "never") {
+			t.Errorf("%s: got %q, %v", name, out, err)
+		}
+		out, err = run("json", "list")
+		var all []client.Link
+		if err != nil || json.Unmarshal([]byte(out), &all) != nil || len(all) == 0 {
+			t.Errorf("%s: got %s, %v", name, out, err)
+		}
+		if out, err = run("table", "delete", l.Code); err != nil || out != "Deleted "+l.Code+"\n" {
+			t.Errorf("%s: got %q, %v", name, out, err)
+		}
+		if _, err = run("table", "resolve", l.Code); err == nil {
+			t.Errorf("%s: the link should be deleted", name)
+		}
+	}
+
+	for _, args := range [][]string{{"shorten"}, {"stats", "a", "b"}, {"rename", "a"}} {
+		if err := runCommand(context.Background(), localStore{s}, commandOptions{format: "table"}, args, ioutil.Discard); err == nil {
+			t.Errorf("%v should fail", args)
+		}
+	}
+	if err := runCommand(context.Background(), localStore{s}, commandOptions{format: "xml"}, []string{"list"}, ioutil.Discard); err == nil {
+		t.Error("Unknown formats should fail")
+	}
+}
+
+func TestImportExportCommands(t *testing.T) {
+	s := newTestServer(t)
+	s.Shorten("https://example.com/exported")
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "urlss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, links := range map[string]linkStore{
+		"local":  localStore{s},
+		"remote": &client.Client{BaseURL: ts.URL, Token: "test-token"},
+	} {
+		run := func(opts client.ImportOptions, args ...string) (string, error) {
+			var buf bytes.Buffer
+			err := runCommand(context.Background(), links, commandOptions{format: "table", imports: opts}, args, &buf)
+			return buf.String(), err
+		}
+		file := filepath.Join(dir, name+".csv")
+		if out, err := run(client.ImportOptions{}, "export", file); err != nil || out != "Exported to "+file+"\n" {
+			t.Fatalf("%s: got %q, %v", name, out, err)
+		}
+		out, err := run(client.ImportOptions{Conflict: "rename", DryRun: true}, "import", file)
+		if rows := strings.Split(out, "\n"); err != nil || rows[0] != "Dry run, nothing was changed" || strings.Join(strings.Fields(rows[2]), " ") != "0 0 1 0 0" {
+			t.Errorf("%s: got %q, %v", name, out, err)
+		}
+		out, err = run(client.ImportOptions{}, "import", file)
+		if rows := strings.Split(out, "\n"); err != nil || strings.Join(strings.Fields(rows[1]), " ") != "0 0 0 1 0" {
+			t.Errorf("%s: got %q, %v", name, out, err)
+		}
+		if _, err = run(client.ImportOptions{}, "export", filepath.Join(dir, name+".txt")); err == nil {
+			t.Errorf("%s: files without a known extension should fail", name)
+		}
+	}
+	if len(s.Codes()) != 1 {
+		t.Errorf("Got %v", s.Codes())
+	}
+
+	dump := filepath.Join(dir, "yourls.sql")
+	ioutil.WriteFile(dump, []byte("INSERT INTO `yourls_url` VALUES ('old','https://example.com/old','Old','2015-01-01 00:00:00','',42);"), 0644)
+	export := filepath.Join(dir, "shlink.csv")
+	ioutil.WriteFile(export, []byte("shortCode,longUrl,visits\nsh,https://example.com/shlink,3\n"), 0644)
+	if err = runCommand(context.Background(), localStore{s}, commandOptions{format: "table"}, []string{"import", dump}, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if err = runCommand(context.Background(), localStore{s}, commandOptions{format: "table", from: "shlink"}, []string{"import", export}, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if s.Stats("old").Clicks != 42 || s.Stats("sh").Clicks != 3 {
+		t.Errorf("Got %v", s.Codes())
+	}
+}
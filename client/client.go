@@ -0,0 +1,308 @@
+// Package client talks to the JSON API of a urlss server:
+//
+//	c := client.New("https://urls.example.com")
+//	link, err := c.Shorten(ctx, "https://example.com/a/long/page")
+//
+// Requests that fail with 429 or a 5xx status, or that could not
+// be sent, are retried with exponential backoff.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client is a client of one urlss server
+type Client struct {
+	// BaseURL is the address of the server, including its prefix
+	BaseURL string
+	// Token is the api_token of the server, which every
+	// request but Create, Shorten and Stats needs
+	Token      string
+	HTTPClient *http.Client
+	// Retries is how often a failed request is tried again,
+	// waiting Backoff before the first retry and twice as
+	// long before each next one
+	Retries int
+	Backoff time.Duration
+}
+
+// New returns a client for the server at baseURL
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+		Retries:    3,
+		Backoff:    200 * time.Millisecond,
+	}
+}
+
+// Link is a link as the API returns it, without the hash of its password
+type Link struct {
+	Code      string `json:"code"`
+	Protected bool   `json:"protected"`
+	Options
+	// Path and Expires are the token of a signed link,
+	// only Create sets them
+	Path    string    `json:"-"`
+	Expires time.Time `json:"-"`
+}
+
+// NewLink is a link to create, with its options
+type NewLink struct {
+	Options
+	// Password protects the link, it is stored hashed
+	Password string `json:"password,omitempty"`
+	// ExpiresIn is how long the token of a signed link lasts, such as "24h"
+	ExpiresIn string `json:"expires_in,omitempty"`
+}
+
+// Stats are the clicks of a link
+type Stats struct {
+	Code     string            `json:"code"`
+	Stats    Counts            `json:"stats"`
+	Params   map[string]string `json:"params"`
+	Variants []VariantStats    `json:"variants"`
+}
+
+// Page is a part of the list of all links
+type Page struct {
+	Links  []Link `json:"links"`
+	Offset int    `json:"offset"`
+	Total  int    `json:"total"`
+}
+
+// Error is an error answered by the server
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("urlss: %d %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is the answer for an unknown code
+func IsNotFound(err error) bool {
+	e, ok := err.(*Error)
+	return ok && e.StatusCode == http.StatusNotFound
+}
+
+// Shorten creates a plain link to rawURL, or returns the
+// one that was made for it before
+func (c *Client) Shorten(ctx context.Context, rawURL string) (*Link, error) {
+	return c.Create(ctx, NewLink{Options: Options{URL: rawURL}})
+}
+
+// Create makes a link with options
+func (c *Client) Create(ctx context.Context, l NewLink) (*Link, error) {
+	var raw json.RawMessage
+	if err := c.do(ctx, "POST", "/api/links", l, &raw); err != nil {
+		return nil, err
+	}
+	if !l.Signed {
+		var created Link
+		if err := json.Unmarshal(raw, &created); err != nil {
+			return nil, err
+		}
+		return &created, nil
+	}
+	// signed links are answered together with their token
+	var signed struct {
+		Link    Link      `json:"link"`
+		Path    string    `json:"path"`
+		Expires time.Time `json:"expires"`
+	}
+	if err := json.Unmarshal(raw, &signed); err != nil {
+		return nil, err
+	}
+	signed.Link.Path = signed.Path
+	signed.Link.Expires = signed.Expires
+	return &signed.Link, nil
+}
+
+// Get returns the link of a code
+func (c *Client) Get(ctx context.Context, code string) (*Link, error) {
+	var l Link
+	if err := c.do(ctx, "GET", "/api/links/"+url.PathEscape(code), nil, &l); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// Update changes the options of a link, keeping its code
+func (c *Client) Update(ctx context.Context, code string, u Update) (*Link, error) {
+	var l Link
+	if err := c.do(ctx, "PATCH", "/api/links/"+url.PathEscape(code), u, &l); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// Delete removes a link and its stats
+func (c *Client) Delete(ctx context.Context, code string) error {
+	return c.do(ctx, "DELETE", "/api/links/"+url.PathEscape(code), nil, nil)
+}
+
+// Stats returns the clicks of a link
+func (c *Client) Stats(ctx context.Context, code string) (*Stats, error) {
+	var stats Stats
+	if err := c.do(ctx, "GET", "/api/links/"+url.PathEscape(code)+"/stats", nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// List returns up to limit links in the order of their codes,
+// starting at offset. A limit of 0 is the server's default.
+func (c *Client) List(ctx context.Context, offset, limit int) (*Page, error) {
+	query := url.Values{"offset": {strconv.Itoa(offset)}}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var page Page
+	if err := c.do(ctx, "GET", "/api/links?"+query.Encode(), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Export writes every link to w in format, csv, json or jsonl,
+// as the server streams them
+func (c *Client) Export(ctx context.Context, format string, w io.Writer, opts ExportOptions) error {
+	query := url.Values{"format": {format}, "password_hashes": {strconv.FormatBool(opts.PasswordHashes)}}
+	resp, err := c.open(ctx, "GET", "/api/export?"+query.Encode(), nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// Import adds the records read from r in format, csv, json or
+// jsonl, and returns what the server did with them
+func (c *Client) Import(ctx context.Context, format string, r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{"format": {format}, "conflict": {opts.Conflict}, "dry_run": {strconv.FormatBool(opts.DryRun)}}
+	resp, err := c.open(ctx, "POST", "/api/import?"+query.Encode(), body, "text/plain")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var report ImportReport
+	if err = json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// do sends a request with in as its JSON body and decodes
+// the answer into out
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+	resp, err := c.open(ctx, method, path, body, "application/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil || out == nil || len(data) == 0 {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// open sends a request, retrying as configured, and returns
+// the answer if it succeeded
+func (c *Client) open(ctx context.Context, method, path string, body []byte, contentType string) (*http.Response, error) {
+	wait := c.Backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, path, body, contentType)
+		if attempt >= c.Retries || !retryable(method, err) {
+			return resp, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, path string, body []byte, contentType string) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, c.BaseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	var answer struct {
+		Error string `json:"error"`
+	}
+	if json.NewDecoder(resp.Body).Decode(&answer) != nil || answer.Error == "" {
+		answer.Error = http.StatusText(resp.StatusCode)
+	}
+	return nil, &Error{resp.StatusCode, answer.Error}
+}
+
+// retryable reports whether a request that failed with err may
+// succeed when sent again. Creating a link is only retried when
+// the server refused it, as it may have been made otherwise.
+func retryable(method string, err error) bool {
+	switch e := err.(type) {
+	case nil:
+		return false
+	case *Error:
+		if e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusServiceUnavailable {
+			return true
+		}
+		return e.StatusCode >= 500 && method != "POST"
+	default:
+		if err == context.Canceled || err == context.DeadlineExceeded {
+			return false
+		}
+		return method != "POST"
+	}
+}
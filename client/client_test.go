@@ -0,0 +1,245 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/schollz/jsonstore"
+	"github.com/schollz/urlss/urlss"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// testToken is the API token of the test servers
+const testToken = "test-token"
+
+// newTestServer runs a urlss server with an empty store
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "urlss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := urlss.DefaultConfig()
+	cfg.Data = filepath.Join(dir, "urls.json.gz")
+	cfg.APIToken = testToken
+	s, err := urlss.NewServer(cfg, new(jsonstore.JSONStore), urlss.NewLogger(ioutil.Discard, urlss.LogConfig{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	t.Cleanup(func() {
+		ts.Close()
+		os.RemoveAll(dir)
+	})
+	return ts
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	c := New(newTestServer(t).URL + "/")
+	c.Token = testToken
+
+	plain, err := c.Shorten(ctx, "https://example.com/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.Code == "" || plain.URL != "https://example.com/page" {
+		t.Errorf("Got %+v", plain)
+	}
+	again, err := c.Shorten(ctx, "https://example.com/page")
+	if err != nil || again.Code != plain.Code {
+		t.Errorf("Got %+v, %v", again, err)
+	}
+
+	l, err := c.Create(ctx, NewLink{Options: Options{URL: "https://docs.example.com/", Prefix: true}, Password: "hunter2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !l.Prefix || !l.Protected {
+		t.Errorf("Got %+v", l)
+	}
+	signed, err := c.Create(ctx, NewLink{Options: Options{URL: "https://files.example.com/", Signed: true}, ExpiresIn: "1h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signed.Code == "" || signed.Path == "" || signed.Expires.Before(time.Now()) {
+		t.Errorf("Got %+v", signed)
+	}
+
+	params := map[string]string{"utm_source": "sdk"}
+	updated, err := c.Update(ctx, plain.Code, Update{Params: &params})
+	if err != nil || updated.Params["utm_source"] != "sdk" {
+		t.Errorf("Got %+v, %v", updated, err)
+	}
+	got, err := c.Get(ctx, plain.Code)
+	if err != nil || got.Params["utm_source"] != "sdk" {
+		t.Errorf("Got %+v, %v", got, err)
+	}
+
+	stats, err := c.Stats(ctx, plain.Code)
+	if err != nil || stats.Code != plain.Code || stats.Stats.Clicks != 0 || stats.Params["utm_source"] != "sdk" {
+		t.Errorf("Got %+v, %v", stats, err)
+	}
+
+	page, err := c.List(ctx, 0, 2)
+	if err != nil || page.Total != 3 || len(page.Links) != 2 {
+		t.Errorf("Got %+v, %v", page, err)
+	}
+	rest, err := c.List(ctx, 2, 0)
+	if err != nil || len(rest.Links) != 1 || rest.Links[0].Code == page.Links[1].Code {
+		t.Errorf("Got %+v, %v", rest, err)
+	}
+
+	anonymous := New(c.BaseURL)
+	if _, err = anonymous.Get(ctx, plain.Code); err == nil || err.(*Error).StatusCode != http.StatusUnauthorized {
+		t.Errorf("Got %v without the token", err)
+	}
+
+	if err = c.Delete(ctx, plain.Code); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.Get(ctx, plain.Code); !IsNotFound(err) {
+		t.Errorf("Got %v for a deleted link", err)
+	}
+	if err = c.Delete(ctx, plain.Code); !IsNotFound(err) {
+		t.Errorf("Got %v deleting twice", err)
+	}
+	if _, err = c.Shorten(ctx, "javascript:alert(1)"); err == nil || err.(*Error).StatusCode != http.StatusForbidden {
+		t.Errorf("Got %v for a refused scheme", err)
+	}
+}
+
+func TestImportExport(t *testing.T) {
+	ctx := context.Background()
+	c := New(newTestServer(t).URL)
+	c.Token = testToken
+	report, err := c.Import(ctx, "csv", strings.NewReader("code,url\ndocs,https://docs.example.com/\n,https://example.com/\n"), ImportOptions{})
+	if err != nil || report.Created != 2 {
+		t.Fatalf("Got %+v, %v", report, err)
+	}
+	report, err = c.Import(ctx, "jsonl", strings.NewReader(`{"code":"docs","url":"https://example.org/"}`), ImportOptions{Conflict: "overwrite", DryRun: true})
+	if err != nil || !report.DryRun || report.Overwritten != 1 {
+		t.Errorf("Got %+v, %v", report, err)
+	}
+	if _, err = c.Import(ctx, "xml", strings.NewReader(""), ImportOptions{}); err == nil || err.(*Error).StatusCode != http.StatusBadRequest {
+		t.Errorf("Got %v for an unknown format", err)
+	}
+
+	var buf bytes.Buffer
+	if err = c.Export(ctx, "jsonl", &buf, ExportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	records, err := urlss.ReadRecords(&buf, "jsonl")
+	if err != nil || len(records) != 2 || records[0].Code != "docs" && records[1].Code != "docs" {
+		t.Errorf("Got %+v, %v", records, err)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	ts := newTestServer(t)
+	var failures, requests int32 = 2, 0
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.AddInt32(&failures, -1) >= 0 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		ts.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer flaky.Close()
+
+	c := New(flaky.URL)
+	c.Token = testToken
+	c.Backoff = time.Millisecond
+	if _, err := c.List(context.Background(), 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Errorf("Expected 3 requests, got %d", n)
+	}
+
+	// creating is only retried when the server refused it
+	atomic.StoreInt32(&failures, 1)
+	atomic.StoreInt32(&requests, 0)
+	if _, err := c.Shorten(context.Background(), "https://example.com"); err == nil || err.(*Error).StatusCode != http.StatusBadGateway {
+		t.Errorf("Got %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Expected 1 request, got %d", n)
+	}
+
+	atomic.StoreInt32(&failures, 100)
+	c.Retries = 2
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	c.Backoff = time.Second
+	start := time.Now()
+	if _, err := c.Get(ctx, "abc"); err != context.DeadlineExceeded {
+		t.Errorf("Got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("The backoff should stop when the context is done")
+	}
+}
+
+// jsonFields are the JSON names of the fields of t
+func jsonFields(t reflect.Type) []string {
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		if name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]; name != "" {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+func TestTypesMatchTheServer(t *testing.T) {
+	for _, types := range []struct {
+		client, server interface{}
+		hidden         string
+	}{
+		{Options{}, urlss.Link{}, "password_hash"},
+		{Rule{}, urlss.Rule{}, ""},
+		{ScheduleEntry{}, urlss.ScheduleEntry{}, ""},
+		{Variant{}, urlss.Variant{}, ""},
+		{Update{}, urlss.LinkUpdate{}, ""},
+		{Counts{}, urlss.Stats{}, ""},
+		{ImportOptions{}, urlss.ImportOptions{}, ""},
+		{ExportOptions{}, urlss.ExportOptions{}, ""},
+		{ImportReport{}, urlss.ImportReport{}, ""},
+		{ImportError{}, urlss.ImportError{}, ""},
+	} {
+		var server []string
+		for _, field := range jsonFields(reflect.TypeOf(types.server)) {
+			if field != types.hidden {
+				server = append(server, field)
+			}
+		}
+		if client := jsonFields(reflect.TypeOf(types.client)); !reflect.DeepEqual(client, server) {
+			t.Errorf("%T has %v, the server %v", types.client, client, server)
+		}
+	}
+
+	out, err := exec.Command("go", "list", "-deps", ".").Output()
+	if err != nil {
+		t.Skip(err)
+	}
+	if strings.Contains(string(out), "github.com/schollz/urlss/urlss") || strings.Contains(string(out), "gin-gonic") {
+		t.Errorf("The client should not import the server, got %s", out)
+	}
+}
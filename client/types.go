@@ -0,0 +1,127 @@
+package client
+
+import "time"
+
+// The types below are the JSON of the API. They mirror the types
+// of the urlss package, which the client does not import so that
+// programs using it don't build the server.
+
+// Options are the options of a link, see urlss.Link
+type Options struct {
+	URL string `json:"url"`
+	// Title is a label for people, it does not change redirects
+	Title string `json:"title,omitempty"`
+	// Prefix merges the path and query appended to the
+	// short code into URL, so /code/a/b?x=1 redirects to URL/a/b?x=1
+	Prefix bool `json:"prefix,omitempty"`
+	// QueryConflict decides which value wins when a prefix link and
+	// the request share a query parameter: "request" (the default),
+	// "link", or "both" to keep them all
+	QueryConflict string `json:"query_conflict,omitempty"`
+	// Params are campaign parameters added to the destination
+	Params map[string]string `json:"params,omitempty"`
+	// Rules are tried in order and the first that matches the
+	// request replaces URL, which is the fallback
+	Rules []Rule `json:"rules,omitempty"`
+	// Schedule replaces URL from the start of each entry on, when
+	// no rule matched. Starts without an offset are in Timezone.
+	Schedule []ScheduleEntry `json:"schedule,omitempty"`
+	Timezone string          `json:"timezone,omitempty"`
+	// Variants split the remaining requests between weighted URLs,
+	// chosen per Rotation and kept per visitor if Sticky
+	Variants []Variant `json:"variants,omitempty"`
+	Rotation string    `json:"rotation,omitempty"`
+	Sticky   bool      `json:"sticky,omitempty"`
+	// Signed links only redirect through expiring tokens
+	Signed bool `json:"signed,omitempty"`
+}
+
+// Rule sends requests that match all of its conditions to its own URL
+type Rule struct {
+	// Platform is one of ios, android, windows, macos, linux,
+	// or mobile and desktop for any of the former
+	Platform string `json:"platform,omitempty"`
+	// Language is a BCP 47 tag matched against the most
+	// preferred Accept-Language
+	Language string `json:"language,omitempty"`
+	URL      string `json:"url"`
+}
+
+// ScheduleEntry sends a link to URL from Start on, until the next entry
+type ScheduleEntry struct {
+	// Start is RFC 3339, or "2006-01-02 15:04" in the link's Timezone
+	Start string `json:"start"`
+	URL   string `json:"url"`
+}
+
+// Variant is one of the weighted destinations of a split link
+type Variant struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// Update changes the options of a link, nil fields are kept
+type Update struct {
+	Title    *string            `json:"title"`
+	Params   *map[string]string `json:"params"`
+	Rules    *[]Rule            `json:"rules"`
+	Schedule *[]ScheduleEntry   `json:"schedule"`
+	Timezone *string            `json:"timezone"`
+	Variants *[]Variant         `json:"variants"`
+	Rotation *string            `json:"rotation"`
+	Sticky   *bool              `json:"sticky"`
+	// Password replaces the password, "" removes it
+	Password *string `json:"password"`
+}
+
+// Counts are the click analytics of a link
+type Counts struct {
+	Clicks    int64     `json:"clicks"`
+	LastClick time.Time `json:"last_click,omitempty"`
+	// Variants counts the clicks sent to each variant of a split link
+	Variants []int64 `json:"variants,omitempty"`
+	// Created is when the link was made, if it is known
+	Created time.Time `json:"created,omitempty"`
+}
+
+// VariantStats are the clicks of one variant of a split link
+type VariantStats struct {
+	Variant
+	Clicks int64 `json:"clicks"`
+}
+
+// ImportOptions shape Import
+type ImportOptions struct {
+	// Conflict is what happens to records whose code is taken:
+	// "skip" them (the default), "overwrite" the link, or
+	// "rename" them to a new code
+	Conflict string `json:"conflict"`
+	// DryRun reports what would happen without changing anything
+	DryRun bool `json:"dry_run"`
+}
+
+// ExportOptions shape Export
+type ExportOptions struct {
+	// PasswordHashes keeps the password hashes of protected
+	// links, which are left out otherwise
+	PasswordHashes bool `json:"password_hashes"`
+}
+
+// ImportReport is what Import did, or would do in a dry run
+type ImportReport struct {
+	DryRun      bool `json:"dry_run"`
+	Created     int  `json:"created"`
+	Overwritten int  `json:"overwritten"`
+	Skipped     int  `json:"skipped"`
+	// Renamed maps the codes of the renamed records to their new codes
+	Renamed map[string]string `json:"renamed"`
+	Failed  []ImportError     `json:"failed"`
+}
+
+// ImportError is a record that could not be imported
+type ImportError struct {
+	// Record counts the records from 1
+	Record int    `json:"record"`
+	Code   string `json:"code"`
+	Error  string `json:"error"`
+}
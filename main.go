@@ -1,164 +1,175 @@
 package main
 
 import (
-	"errors"
+	"context"
 	"flag"
 	"fmt"
-	"html/template"
+	"io"
 	"log"
-	"math/rand"
 	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
-	"github.com/gin-contrib/multitemplate"
 	"github.com/gin-gonic/gin"
-	"github.com/goware/urlx"
-	"github.com/schollz/jsonstore"
+	"github.com/schollz/urlss/urlss"
+	"gopkg.in/yaml.v2"
 )
 
-var ks *jsonstore.JSONStore
-
-func init() {
-	var err error
-	ks, err = jsonstore.Open("urls.json.gz")
-	if err != nil {
-		ks = new(jsonstore.JSONStore)
-	}
-}
-
-var Port string
-
 func main() {
 	gin.SetMode(gin.ReleaseMode)
-	flag.StringVar(&Port, "p", "8006", "port (default 8006)")
+	cfg := urlss.DefaultConfig()
+	var configPath, port, server string
+	var opts commandOptions
+	flag.StringVar(&configPath, "config", os.Getenv("URLSS_CONFIG"), "YAML configuration file")
+	flag.StringVar(&port, "p", "", "port, short for -listen :port (default 8006)")
+	flag.StringVar(&cfg.Listen, "listen", cfg.Listen, "address to listen on")
+	flag.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", cfg.ShutdownTimeout, "how long requests may take to finish at shutdown")
+	flag.DurationVar(&cfg.FlushInterval, "flush-interval", cfg.FlushInterval, "how often clicks are saved")
+	flag.StringVar(&cfg.Data, "data", cfg.Data, "file the links are stored in")
+	flag.StringVar(&cfg.BaseURL, "base", cfg.BaseURL, "canonical base URL of short links, such as https://urls.example.com")
+	flag.StringVar(&cfg.Prefix, "prefix", cfg.Prefix, "path to serve under, such as /s")
+	flag.StringVar(&cfg.Normalize, "normalize", cfg.Normalize, "comma separated URL normalizations, prefix with - to remove one")
+	flag.StringVar(&cfg.Strip, "strip", cfg.Strip, "comma separated query parameters to strip, * matches a prefix")
+	flag.StringVar(&cfg.Schemes, "schemes", cfg.Schemes, "comma separated URL schemes that can be shortened")
+	flag.StringVar(&cfg.Secret, "secret", cfg.Secret, "secret that signs links and cookies (default random at each start)")
+	flag.StringVar(&cfg.APIToken, "api-token", cfg.APIToken, "bearer token of the API routes that change or reveal links, which are refused without one")
+	flag.StringVar(&cfg.Templates, "templates", cfg.Templates, "directory of templates that replace the built-in ones by name")
+	flag.StringVar(&cfg.Static, "static", cfg.Static, "directory served under /static/")
+	flag.BoolVar(&cfg.Dev, "dev", cfg.Dev, "reload templates on every request")
+	flag.BoolVar(&cfg.TrustProxy, "trust-proxy", cfg.TrustProxy, "take client addresses from X-Forwarded-For, only behind a proxy that sets it")
+	flag.StringVar(&cfg.Log.Format, "log-format", cfg.Log.Format, "format of the log, logfmt or json")
+	flag.StringVar(&cfg.Log.Level, "log-level", cfg.Log.Level, "least severe level logged, debug, info, warn or error")
+	flag.BoolVar(&cfg.Log.RedactURLs, "log-redact-urls", cfg.Log.RedactURLs, "leave destination URLs out of the log")
+	flag.BoolVar(&cfg.Log.RedactIPs, "log-redact-ips", cfg.Log.RedactIPs, "log only the network of client addresses")
+	flag.StringVar(&server, "server", os.Getenv("URLSS_SERVER"), "address of a server the commands manage instead of the data file")
+	flag.StringVar(&opts.format, "format", "table", "output of the commands, table or json")
+	flag.StringVar(&opts.from, "from", "", "format of the imported file, one of "+strings.Join(append(append([]string{}, urlss.Formats...), urlss.ImportFormats...), ", ")+" (default from its extension)")
+	flag.StringVar(&opts.imports.Conflict, "conflict", "skip", "what import does with codes that are taken, skip, overwrite or rename")
+	flag.BoolVar(&opts.imports.DryRun, "dry-run", false, "report what import would do without changing links")
+	flag.BoolVar(&opts.exports.PasswordHashes, "password-hashes", false, "export the password hashes of protected links, which cannot be imported without them")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] [command] [flags]\n\n%s\nFlags:\n", os.Args[0], commandUsage)
+		flag.PrintDefaults()
+	}
 	flag.Parse()
-	r := gin.Default()
-	r.Use(gin.Logger())
-	r.HTMLRender = loadTemplates("index.html")
-	r.GET("/*action", handleAction)
-	// Start server
-	fmt.Println("Listening on port", Port)
-	r.Run(":" + Port) // listen and serve on 0.0.0.0:8080
-}
-
-// handleAction performs the shortening or redirecting
-func handleAction(c *gin.Context) {
-	fmt.Println(c.Request.RequestURI)
-	action := c.Request.RequestURI
-	action = action[1:len(action)]
-	shortened, redirect, err := shortenURL(action)
-	if redirect {
-		c.Redirect(301, shortened)
-	} else {
-		errString := ""
-		if err != nil {
-			errString = err.Error()
-		}
-		c.HTML(http.StatusOK, "index.html", gin.H{
-			"shortened": shortened,
-			"error":     errString,
-		})
+	args := commandArgs()
+	err := loadSettings(&cfg, configPath)
+	if err != nil {
+		log.Fatal(err)
 	}
-}
-
-func shortenURL(requestURL string) (shortened string, redirect bool, err error) {
-	if strings.Contains(requestURL, "http") && !strings.Contains(requestURL, "//") {
-		requestURL = strings.Replace(requestURL, "/", "//", 1)
-	}
-	parsedURL, _ := urlx.Parse(requestURL)
-	url, _ := urlx.Normalize(parsedURL)
-	if len(url) > 0 && !strings.Contains(url, "favicon") {
-		// Check if it is already a URL
-		errFound := ks.Get(url, &shortened)
-		if errFound != nil {
-			// Get a new shortend URL
-			shortened = newShortenedURL()
-			ks.Set(url, shortened)
-			ks.Set(shortened, url)
-			go jsonstore.Save(ks, "urls.json.gz")
-			log.Printf("Shortened %s to %s", url, shortened)
+	if port != "" {
+		cfg.Listen = ":" + port
+	}
+	if err = cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	if len(args) > 0 && args[0] == "print-config" {
+		if err = printConfig(os.Stdout, cfg); err != nil {
+			log.Fatal(err)
 		}
-	} else {
-		// Redirect the URL if it is shortened
-		err = ks.Get(requestURL, &shortened)
-		if err == nil {
-			redirect = true
-			log.Printf("Redirect %s to %s", requestURL, shortened)
-		} else {
-			if requestURL == "" {
-				err = nil
-			} else {
-				err = errors.New("Could not find " + requestURL)
-			}
+		return
+	}
+	if len(args) > 0 && args[0] != "serve" {
+		if err = manageLinks(cfg, server, opts, args); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
+		return
+	}
+	logger := urlss.NewLogger(os.Stderr, cfg.Log)
+	if cfg.Secret == "" {
+		logger.Warn("No secret given, signed links will stop working at restart")
+	}
+	s, err := urlss.NewServer(cfg, nil, logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Start server
+	logger.Info("Listening", "address", cfg.Listen)
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	if err = serve(&http.Server{Addr: cfg.Listen, Handler: s}, s, logger, cfg.ShutdownTimeout, stop); err != nil {
+		logger.Error("Stopped", "error", err)
+		os.Exit(1)
 	}
-	return
+	logger.Info("Stopped")
 }
 
-// From http://stackoverflow.com/questions/22892120/how-to-generate-a-random-string-of-a-fixed-length-in-golang
-const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
-const (
-	letterIdxBits = 6                    // 6 bits to represent a letter index
-	letterIdxMask = 1<<letterIdxBits - 1 // All 1-bits, as many as letterIdxBits
-	letterIdxMax  = 63 / letterIdxBits   // # of letter indices fitting in 63 bits
-)
-
-var src = rand.NewSource(time.Now().UnixNano())
-
-func RandString(n int) string {
-	b := make([]byte, n)
-	// A src.Int63() generates 63 random bits, enough for letterIdxMax characters!
-	for i, cache, remain := n-1, src.Int63(), letterIdxMax; i >= 0; {
-		if remain == 0 {
-			cache, remain = src.Int63(), letterIdxMax
-		}
-		if idx := int(cache & letterIdxMask); idx < len(letterBytes) {
-			b[i] = letterBytes[idx]
-			i--
-		}
-		cache >>= letterIdxBits
-		remain--
+// serve runs srv until it fails or a signal arrives on stop. It then
+// stops listening, lets the requests finish for up to timeout and
+// waits for s to save the links. Another signal exits at once.
+func serve(srv *http.Server, s *urlss.Server, logger *urlss.Logger, timeout time.Duration, stop chan os.Signal) error {
+	failed := make(chan error, 1)
+	go func() {
+		failed <- srv.ListenAndServe()
+	}()
+	select {
+	case err := <-failed:
+		return err
+	case sig := <-stop:
+		signal.Stop(stop)
+		logger.Info("Shutting down", "signal", sig)
 	}
-
-	return string(b)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := srv.Shutdown(ctx)
+	if err == context.DeadlineExceeded {
+		err = fmt.Errorf("requests still running after %v", timeout)
+	}
+	if closeErr := s.Close(); err == nil {
+		err = closeErr
+	}
+	return err
 }
 
-// newShortenedURL generates a shortened URL,
-// stochastically, checking for collisions until
-// a selects a free one
-func newShortenedURL() string {
-	for n := 1; n < 10; n++ {
-		for i := 0; i < 10; i++ {
-			candidate := RandString(n)
-			var foo string
-			err := ks.Get(candidate, &foo)
-			if err != nil {
-				return candidate
-			}
-		}
+// commandArgs returns the command and its arguments, parsing
+// the flags given between and after them
+func commandArgs() []string {
+	var args []string
+	for rest := flag.Args(); len(rest) > 0; rest = flag.Args() {
+		args = append(args, rest[0])
+		flag.CommandLine.Parse(rest[1:])
 	}
-	return ""
+	return args
 }
 
-// loadTemplates will use the built-in assets to
-// load required templates
-func loadTemplates(list ...string) multitemplate.Render {
-	r := multitemplate.New()
-
-	for _, x := range list {
-		templateString, err := Asset("templates/" + x)
-		if err != nil {
-			panic(err)
+// loadSettings reads the file at path, if any, and the environment
+// into cfg, keeping the flags that were given and parsed into it
+func loadSettings(cfg *urlss.Config, path string) error {
+	given := map[string]string{}
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = f.Value.String()
+	})
+	if path != "" {
+		if err := urlss.LoadConfig(cfg, path); err != nil {
+			return err
 		}
-
-		tmplMessage, err := template.New(x).Parse(string(templateString))
-		if err != nil {
-			panic(err)
-		}
-
-		r.Add(x, tmplMessage)
 	}
+	if err := urlss.LoadEnv(cfg, os.Getenv); err != nil {
+		return err
+	}
+	for name, value := range given {
+		flag.Set(name, value)
+	}
+	return nil
+}
 
-	return r
+// printConfig writes the configuration as YAML, without the
+// secret and the API token
+func printConfig(w io.Writer, cfg urlss.Config) error {
+	if cfg.Secret != "" {
+		cfg.Secret = "(hidden)"
+	}
+	if cfg.APIToken != "" {
+		cfg.APIToken = "(hidden)"
+	}
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
 }
@@ -1,58 +1,83 @@
-package main
-
-import (
-	"os"
-	"testing"
-
-	"github.com/gin-gonic/gin"
-)
-
-func TestHandleAction(t *testing.T) {
-	os.Remove("urls.json.gz")
-	shortened, redirect, err := shortenURL("www.google.com")
-	if len(shortened) != 1 {
-		t.Errorf("Got %s for some reason", shortened)
-	}
-	if redirect == true {
-		t.Error("Incorrectly redirecting")
-	}
-	if err != nil {
-		t.Error(err)
-	}
-
-	s := newShortenedURL()
-	if s == shortened {
-		t.Error("New shortened URL should be different")
-	}
-
-	shortened, redirect, err = shortenURL(shortened)
-	if shortened != "http://www.google.com" {
-		t.Errorf("Got %s for some reason", shortened)
-	}
-	if redirect == false {
-		t.Error("Incorrectly NOT redirecting")
-	}
-	if err != nil {
-		t.Error(err)
-	}
-	shortened, redirect, err = shortenURL("asldkfjaslkdf")
-	if err == nil {
-		t.Error("Should throw error!")
-	}
-
-}
-
-func TestUtils(t *testing.T) {
-	if len(RandString(10)) != 10 {
-		t.Error("RandString is weird")
-	}
-	if RandString(3) == RandString(3) {
-		t.Error("RandString should be different")
-	}
-
-	gin.SetMode(gin.ReleaseMode)
-	r := gin.Default()
-	r.Use(gin.Logger())
-	r.HTMLRender = loadTemplates("index.html")
-	r.GET("/*action", handleAction)
-}
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/schollz/urlss/urlss"
+	"gopkg.in/yaml.v2"
+)
+
+func TestPrintConfig(t *testing.T) {
+	cfg := urlss.DefaultConfig()
+	cfg.Secret = "hunter2"
+	cfg.APIToken = "correct-horse"
+	var buf bytes.Buffer
+	if err := printConfig(&buf, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "hunter2") || strings.Contains(buf.String(), "correct-horse") {
+		t.Error("The secret and the API token should be hidden")
+	}
+	printed := urlss.DefaultConfig()
+	if err := yaml.UnmarshalStrict(buf.Bytes(), &printed); err != nil || printed.Security != cfg.Security || printed.Listen != cfg.Listen {
+		t.Errorf("The printed configuration should load back, got %v", err)
+	}
+}
+
+func TestServe(t *testing.T) {
+	s := newTestServer(t)
+	for _, timeout := range []time.Duration{time.Second, 10 * time.Millisecond} {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr := l.Addr().String()
+		l.Close()
+		started := make(chan bool)
+		srv := &http.Server{Addr: addr, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			started <- true
+			time.Sleep(100 * time.Millisecond)
+		})}
+		stop := make(chan os.Signal, 1)
+		served := make(chan error)
+		go func() {
+			served <- serve(srv, s, urlss.NewLogger(ioutil.Discard, urlss.LogConfig{}), timeout, stop)
+		}()
+
+		answered := make(chan error, 1)
+		go func() {
+			for {
+				resp, err := http.Get("http://" + addr)
+				if err == nil {
+					resp.Body.Close()
+					answered <- nil
+					return
+				}
+				if !strings.Contains(err.Error(), "refused") {
+					answered <- err
+					return
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}()
+		<-started
+		stop <- os.Interrupt
+		err = <-served
+		if timeout == time.Second && (err != nil || <-answered != nil) {
+			t.Errorf("Requests should finish before shutting down, got %v", err)
+		}
+		if timeout < time.Second && err == nil {
+			t.Error("Requests that outlast the timeout should fail the shutdown")
+		}
+	}
+	if _, err := s.Shorten("https://example.com"); err == nil {
+		t.Error("The store should refuse changes after shutting down")
+	}
+}
@@ -0,0 +1,254 @@
+// Package qrcode encodes bytes as QR codes (ISO/IEC 18004) and
+// renders them as PNG or SVG. It only uses byte mode, which is
+// all that short URLs need.
+package qrcode
+
+import (
+	"errors"
+)
+
+// Level is the error correction level of a code
+type Level int
+
+// The error correction levels, from about 7% to 30% of
+// the code that can be damaged and still be read
+const (
+	L Level = iota
+	M
+	Q
+	H
+)
+
+// ParseLevel reads a level from its letter
+func ParseLevel(s string) (Level, error) {
+	switch s {
+	case "L", "l":
+		return L, nil
+	case "M", "m":
+		return M, nil
+	case "Q", "q":
+		return Q, nil
+	case "H", "h":
+		return H, nil
+	}
+	return 0, errors.New("qrcode: unknown level " + s)
+}
+
+// formatBits are the two bits that stand for each level
+var formatBits = [4]int{1, 0, 3, 2}
+
+// eccPerBlock and numBlocks are indexed by level and version
+var eccPerBlock = [4][41]int{
+	{-1, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	{-1, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+var numBlocks = [4][41]int{
+	{-1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	{-1, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	{-1, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	{-1, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+// ErrTooLong is returned for data that does not fit in version 40
+var ErrTooLong = errors.New("qrcode: data too long")
+
+// Code is an encoded QR code
+type Code struct {
+	// Size is the width and height in modules
+	Size    int
+	Version int
+	Level   Level
+	Mask    int
+	modules []bool
+}
+
+// Black reports whether the module at column x and row y is dark
+func (c *Code) Black(x, y int) bool {
+	return x >= 0 && y >= 0 && x < c.Size && y < c.Size && c.modules[y*c.Size+x]
+}
+
+// Encode returns the smallest QR code holding data at the level,
+// with the mask that scores the lowest penalty
+func Encode(data []byte, level Level) (*Code, error) {
+	return encode(data, level, -1)
+}
+
+func encode(data []byte, level Level, mask int) (*Code, error) {
+	if level < L || level > H {
+		return nil, errors.New("qrcode: unknown level")
+	}
+	version := 1
+	for ; ; version++ {
+		if version > 40 {
+			return nil, ErrTooLong
+		}
+		if 4+countBits(version)+8*len(data) <= 8*numDataCodewords(version, level) {
+			break
+		}
+	}
+	codewords := addErrorCorrection(dataCodewords(data, version, level), version, level)
+
+	m := newMatrix(version)
+	m.drawFunctionPatterns()
+	m.drawCodewords(codewords)
+	if mask < 0 {
+		best := 0
+		for i := 0; i < 8; i++ {
+			m.applyMask(i)
+			m.drawFormatBits(level, i)
+			if penalty := m.penalty(); mask < 0 || penalty < best {
+				mask, best = i, penalty
+			}
+			m.applyMask(i)
+		}
+	}
+	m.applyMask(mask)
+	m.drawFormatBits(level, mask)
+	return &Code{Size: m.size, Version: version, Level: level, Mask: mask, modules: m.modules}, nil
+}
+
+// countBits is the length of the character count in byte mode
+func countBits(version int) int {
+	if version < 10 {
+		return 8
+	}
+	return 16
+}
+
+// numRawModules is the number of modules left for codewords
+func numRawModules(version int) int {
+	n := (16*version+128)*version + 64
+	if version >= 2 {
+		numAlign := version/7 + 2
+		n -= (25*numAlign-10)*numAlign - 55
+		if version >= 7 {
+			n -= 36
+		}
+	}
+	return n
+}
+
+func numDataCodewords(version int, level Level) int {
+	return numRawModules(version)/8 - eccPerBlock[level][version]*numBlocks[level][version]
+}
+
+// dataCodewords builds the byte mode segment, terminated and padded
+func dataCodewords(data []byte, version int, level Level) []byte {
+	capacity := numDataCodewords(version, level)
+	var bb bitBuffer
+	bb.append(4, 4)
+	bb.append(len(data), countBits(version))
+	for _, b := range data {
+		bb.append(int(b), 8)
+	}
+	terminator := 8*capacity - len(bb)
+	if terminator > 4 {
+		terminator = 4
+	}
+	bb.append(0, terminator)
+	bb.append(0, (8-len(bb)%8)%8)
+	codewords := bb.bytes()
+	for pad := byte(0xEC); len(codewords) < capacity; pad ^= 0xEC ^ 0x11 {
+		codewords = append(codewords, pad)
+	}
+	return codewords
+}
+
+type bitBuffer []bool
+
+func (bb *bitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		*bb = append(*bb, (value>>uint(i))&1 == 1)
+	}
+}
+
+func (bb bitBuffer) bytes() []byte {
+	b := make([]byte, len(bb)/8)
+	for i, bit := range bb {
+		if bit {
+			b[i/8] |= 1 << uint(7-i%8)
+		}
+	}
+	return b
+}
+
+// addErrorCorrection splits the data into blocks, adds their
+// Reed-Solomon codewords and interleaves them
+func addErrorCorrection(data []byte, version int, level Level) []byte {
+	blocks := numBlocks[level][version]
+	eccLen := eccPerBlock[level][version]
+	rawCodewords := numRawModules(version) / 8
+	numShort := blocks - rawCodewords%blocks
+	shortLen := rawCodewords / blocks
+
+	divisor := rsDivisor(eccLen)
+	all := make([][]byte, blocks)
+	for i, k := 0, 0; i < blocks; i++ {
+		n := shortLen - eccLen
+		if i >= numShort {
+			n++
+		}
+		block := append([]byte{}, data[k:k+n]...)
+		k += n
+		ecc := rsRemainder(block, divisor)
+		if i < numShort {
+			block = append(block, 0)
+		}
+		all[i] = append(block, ecc...)
+	}
+
+	result := make([]byte, 0, rawCodewords)
+	for i := range all[0] {
+		for j, block := range all {
+			// skip the padding of short blocks
+			if i != shortLen-eccLen || j >= numShort {
+				result = append(result, block[i])
+			}
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func gfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>uint(i))&1) * int(x)
+	}
+	return byte(z)
+}
+
+// rsDivisor returns the generator polynomial of a degree,
+// without its leading 1
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i := range result {
+			result[i] ^= gfMultiply(divisor[i], factor)
+		}
+	}
+	return result
+}
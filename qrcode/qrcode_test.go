@@ -0,0 +1,69 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	c, err := Encode([]byte("https://urls.schollz.com/abc"), M)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Version != 3 || c.Size != 29 {
+		t.Errorf("Got version %d of size %d", c.Version, c.Size)
+	}
+	// the finder patterns and the dark module
+	for _, p := range [][2]int{{0, 0}, {6, 6}, {2, 2}, {28, 0}, {0, 28}, {8, 21}} {
+		if !c.Black(p[0], p[1]) {
+			t.Errorf("%v should be dark", p)
+		}
+	}
+	for _, p := range [][2]int{{1, 1}, {7, 7}, {5, 1}, {7, 0}} {
+		if c.Black(p[0], p[1]) {
+			t.Errorf("%v should be light", p)
+		}
+	}
+
+	if _, err = Encode(make([]byte, 2954), L); err != ErrTooLong {
+		t.Errorf("Got %v for too much data", err)
+	}
+	if c, _ = Encode(make([]byte, 2953), L); c.Version != 40 {
+		t.Errorf("Got version %d for the largest code", c.Version)
+	}
+}
+
+func TestErrorCorrection(t *testing.T) {
+	data := dataCodewords([]byte("01234567"), 1, M)
+	ecc := rsRemainder(data, rsDivisor(eccPerBlock[M][1]))
+	if len(data) != 16 || len(ecc) != 10 {
+		t.Fatalf("Got %d data and %d error correction codewords", len(data), len(ecc))
+	}
+	// a block followed by its error correction divides evenly
+	for i, b := range rsRemainder(append(data, ecc...), rsDivisor(10)) {
+		if b != 0 {
+			t.Errorf("Remainder %d is %d", i, b)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	c, _ := Encode([]byte("hello"), H)
+	img, err := png.Decode(bytes.NewReader(c.PNG(4, 4)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if width := img.Bounds().Dx(); width != (c.Size+8)*4 {
+		t.Errorf("Got width %d", width)
+	}
+	if r, _, _, _ := img.At(16, 16).RGBA(); r != 0 {
+		t.Error("Top left module should be dark")
+	}
+	if r, _, _, _ := img.At(0, 0).RGBA(); r == 0 {
+		t.Error("Quiet zone should be light")
+	}
+	if !bytes.HasPrefix(c.SVG(200, 4), []byte("<svg")) {
+		t.Error("SVG is weird")
+	}
+}
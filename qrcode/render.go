@@ -0,0 +1,55 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// Image draws the code with scale pixels per module and
+// a quiet zone of margin modules around it
+func (c *Code) Image(scale, margin int) *image.Paletted {
+	width := (c.Size + 2*margin) * scale
+	img := image.NewPaletted(image.Rect(0, 0, width, width), color.Palette{color.White, color.Black})
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.Black(x, y) {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				row := img.Pix[((y+margin)*scale+dy)*img.Stride:]
+				for dx := 0; dx < scale; dx++ {
+					row[(x+margin)*scale+dx] = 1
+				}
+			}
+		}
+	}
+	return img
+}
+
+// PNG returns the code as a PNG image, see Image
+func (c *Code) PNG(scale, margin int) []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, c.Image(scale, margin))
+	return buf.Bytes()
+}
+
+// SVG returns the code as an SVG image width pixels wide,
+// with a quiet zone of margin modules around it
+func (c *Code) SVG(width, margin int) []byte {
+	var buf bytes.Buffer
+	n := c.Size + 2*margin
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d" shape-rendering="crispEdges">`, n, n, width, width)
+	buf.WriteString(`<rect width="100%" height="100%" fill="#fff"/><path fill="#000" d="`)
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.Black(x, y) {
+				fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x+margin, y+margin)
+			}
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes()
+}
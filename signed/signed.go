@@ -0,0 +1,70 @@
+// Package signed makes and checks the signed, expiring tokens of
+// urlss. A token is "code.expires.signature", where expires is a
+// base 36 Unix time and signature an HMAC-SHA256 of both, so it can
+// be checked with the server's secret alone, without its store.
+package signed
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalid is returned for malformed or tampered tokens
+	ErrInvalid = errors.New("signed: invalid token")
+	// ErrExpired is returned for genuine tokens past their expiry
+	ErrExpired = errors.New("signed: token expired")
+)
+
+// signatureLength is how many bytes of the HMAC are kept
+const signatureLength = 16
+
+func signature(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("urlss signed token\x00"))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:signatureLength])
+}
+
+// Sign returns a token for code that is valid until expires.
+// Codes must not contain dots.
+func Sign(secret []byte, code string, expires time.Time) string {
+	payload := code + "." + strconv.FormatInt(expires.Unix(), 36)
+	return payload + "." + signature(secret, payload)
+}
+
+// Verify checks a token at the time now and returns its code
+// and expiry. Expired tokens return ErrExpired only if their
+// signature is genuine.
+func Verify(secret []byte, token string, now time.Time) (code string, expires time.Time, err error) {
+	i := strings.LastIndex(token, ".")
+	if i < 0 {
+		err = ErrInvalid
+		return
+	}
+	payload, sig := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(signature(secret, payload))) {
+		err = ErrInvalid
+		return
+	}
+	parts := strings.Split(payload, ".")
+	if len(parts) != 2 {
+		err = ErrInvalid
+		return
+	}
+	unix, err := strconv.ParseInt(parts[1], 36, 64)
+	if err != nil {
+		err = ErrInvalid
+		return
+	}
+	code, expires = parts[0], time.Unix(unix, 0)
+	if now.After(expires) {
+		err = ErrExpired
+	}
+	return
+}
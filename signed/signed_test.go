@@ -0,0 +1,36 @@
+package signed
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Unix(1500000000, 0)
+	token := Sign(secret, "abc", now.Add(time.Hour))
+
+	code, expires, err := Verify(secret, token, now)
+	if err != nil || code != "abc" || !expires.Equal(now.Add(time.Hour)) {
+		t.Errorf("Got %s, %s, %v", code, expires, err)
+	}
+	if _, _, err = Verify(secret, token, now.Add(2*time.Hour)); err != ErrExpired {
+		t.Errorf("Got %v instead of ErrExpired", err)
+	}
+	if _, _, err = Verify([]byte("other"), token, now); err != ErrInvalid {
+		t.Errorf("Got %v for the wrong secret", err)
+	}
+
+	parts := strings.Split(token, ".")
+	for _, tampered := range []string{
+		"abd." + parts[1] + "." + parts[2],
+		parts[0] + ".zzzzzz." + parts[2],
+		parts[0] + "." + parts[1],
+		"",
+	} {
+		if _, _, err = Verify(secret, tampered, now); err != ErrInvalid {
+			t.Errorf("Got %v for %s", err, tampered)
+		}
+	}
+}
@@ -0,0 +1,404 @@
+package urlss
+
+import (
+	"crypto/subtle"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+var (
+	errNoAPIToken   = blockedError(errors.New("This API needs an api_token, which is not set"))
+	errUnauthorized = &statusError{http.StatusUnauthorized, errors.New("This API needs the api_token as a bearer token")}
+)
+
+// authorized reports whether r carries the API token
+func (s *Server) authorized(r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return s.cfg.APIToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.APIToken)) == 1
+}
+
+// requireToken refuses the requests to the routes that change or
+// reveal links without the API token, and all of them if it is not set
+func (s *Server) requireToken(c *gin.Context) {
+	switch {
+	case s.cfg.APIToken == "":
+		c.AbortWithStatusJSON(errorStatus(errNoAPIToken), gin.H{"error": errNoAPIToken.Error()})
+	case !s.authorized(c.Request):
+		c.Header("WWW-Authenticate", "Bearer")
+		c.AbortWithStatusJSON(errorStatus(errUnauthorized), gin.H{"error": errUnauthorized.Error()})
+	}
+}
+
+// hidesDestinations reports whether the destinations of a link
+// are only shown with the API token, as for protected and signed links
+func (l Link) hidesDestinations() bool {
+	return l.PasswordHash != "" || l.Signed
+}
+
+// apiLink is a link as the JSON API returns it,
+// without the hash of its password
+type apiLink struct {
+	Code      string `json:"code"`
+	Protected bool   `json:"protected"`
+	Link
+}
+
+func newAPILink(code string, l Link) apiLink {
+	protected := l.PasswordHash != ""
+	l.PasswordHash = ""
+	return apiLink{code, protected, l}
+}
+
+// publicAPILink is the apiLink for a request, which leaves
+// the destinations out unless it has the API token
+func (s *Server) publicAPILink(c *gin.Context, code string, l Link) apiLink {
+	if l.hidesDestinations() && !s.authorized(c.Request) {
+		l.URL, l.Rules, l.Schedule, l.Variants = "", nil, nil, nil
+	}
+	return newAPILink(code, l)
+}
+
+// handleAPICreate shortens the link given as JSON, with
+// an optional password in the "password" field
+func (s *Server) handleAPICreate(c *gin.Context) {
+	var request struct {
+		Link
+		Password string `json:"password"`
+		// ExpiresIn is how long the token of a signed link lasts
+		ExpiresIn string `json:"expires_in"`
+	}
+	if err := c.ShouldBindWith(&request, binding.JSON); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	l := request.Link
+	l.PasswordHash = ""
+	if request.Password != "" {
+		l.PasswordHash = hashPassword(request.Password)
+	}
+	var expires time.Time
+	if l.Signed {
+		ttl, err := time.ParseDuration(request.ExpiresIn)
+		if err != nil || ttl <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Signed links need a positive expires_in"})
+			return
+		}
+		expires = time.Now().Add(ttl)
+	}
+	code, err := s.AddLink(l)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	l, _ = s.Lookup(code)
+	if !l.Signed {
+		c.JSON(http.StatusCreated, s.publicAPILink(c, code, l))
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"link":    s.publicAPILink(c, code, l),
+		"path":    s.prefix + "/" + s.signLink(code, expires),
+		"expires": expires.UTC(),
+	})
+}
+
+// handleAPISign makes a new expiring token for a link,
+// given either expires_in or an RFC 3339 expires
+func (s *Server) handleAPISign(c *gin.Context) {
+	var request struct {
+		ExpiresIn string    `json:"expires_in"`
+		Expires   time.Time `json:"expires"`
+	}
+	if err := c.ShouldBindWith(&request, binding.JSON); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	code := c.Param("code")
+	if _, err := s.Lookup(code); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Could not find " + code})
+		return
+	}
+	expires := request.Expires
+	if request.ExpiresIn != "" {
+		ttl, err := time.ParseDuration(request.ExpiresIn)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		expires = time.Now().Add(ttl)
+	}
+	if !expires.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tokens need to expire in the future"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"code":    code,
+		"path":    s.prefix + "/" + s.signLink(code, expires),
+		"expires": expires.UTC(),
+	})
+}
+
+// maxPage is the most links the API lists at once
+const maxPage = 1000
+
+// handleAPIList returns the links in the order of their codes,
+// ?limit= of them from ?offset= on
+func (s *Server) handleAPIList(c *gin.Context) {
+	offset, err := intParam(c, "offset", 0, 0, math.MaxInt32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit, err := intParam(c, "limit", 100, 1, maxPage)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	codes := s.Codes()
+	links := []apiLink{}
+	for i := offset; i < len(codes) && len(links) < limit; i++ {
+		if l, err := s.Lookup(codes[i]); err == nil {
+			links = append(links, newAPILink(codes[i], l))
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"links":  links,
+		"offset": offset,
+		"total":  len(codes),
+	})
+}
+
+// handleAPIDelete removes a link and its stats
+func (s *Server) handleAPIDelete(c *gin.Context) {
+	if err := s.DeleteLink(c.Param("code")); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// exportTypes are the content types of the export formats
+var exportTypes = map[string]string{
+	"csv":   "text/csv; charset=utf-8",
+	"json":  "application/json",
+	"jsonl": "application/x-ndjson",
+}
+
+// handleAPIExport streams every link as ?format=
+// csv, json or jsonl (the default), with the password
+// hashes if ?password_hashes= says so
+func (s *Server) handleAPIExport(c *gin.Context) {
+	format := c.DefaultQuery("format", "jsonl")
+	if err := checkFormat(format); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Type", exportTypes[format])
+	c.Header("Content-Disposition", `attachment; filename="links.`+format+`"`)
+	c.Status(http.StatusOK)
+	hashes, _ := strconv.ParseBool(c.Query("password_hashes"))
+	if err := s.Export(c.Writer, format, ExportOptions{PasswordHashes: hashes}); err != nil {
+		s.log.Error("Could not export the links", "request_id", c.GetString(requestIDKey), "error", err)
+	}
+}
+
+// handleAPIImport imports the records in the body, in the
+// ?format= of the export, as ?conflict= and ?dry_run= say
+func (s *Server) handleAPIImport(c *gin.Context) {
+	records, err := ReadRecords(c.Request.Body, c.DefaultQuery("format", "jsonl"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+	report, err := s.Import(records, ImportOptions{Conflict: c.Query("conflict"), DryRun: dryRun})
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// handleAPIGet returns the link for a code
+func (s *Server) handleAPIGet(c *gin.Context) {
+	l, err := s.Lookup(c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Could not find " + c.Param("code")})
+		return
+	}
+	c.JSON(http.StatusOK, newAPILink(c.Param("code"), l))
+}
+
+// LinkUpdate holds the fields of a link that can be
+// changed after it is made, missing fields are kept
+type LinkUpdate struct {
+	Title    *string            `json:"title"`
+	Params   *map[string]string `json:"params"`
+	Rules    *[]Rule            `json:"rules"`
+	Schedule *[]ScheduleEntry   `json:"schedule"`
+	Timezone *string            `json:"timezone"`
+	Variants *[]Variant         `json:"variants"`
+	Rotation *string            `json:"rotation"`
+	Sticky   *bool              `json:"sticky"`
+	// Password replaces the password, "" removes it
+	Password *string `json:"password"`
+}
+
+// handleAPIUpdate changes the options of a link, keeping its code
+func (s *Server) handleAPIUpdate(c *gin.Context) {
+	var update LinkUpdate
+	if err := c.ShouldBindWith(&update, binding.JSON); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	code := c.Param("code")
+	if _, err := s.Lookup(code); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Could not find " + code})
+		return
+	}
+	l, err := s.UpdateLink(code, func(l *Link) error {
+		if update.Title != nil {
+			l.Title = *update.Title
+		}
+		if update.Params != nil {
+			l.Params = *update.Params
+		}
+		if update.Rules != nil {
+			l.Rules = *update.Rules
+		}
+		if update.Schedule != nil {
+			l.Schedule = *update.Schedule
+		}
+		if update.Timezone != nil {
+			l.Timezone = *update.Timezone
+		}
+		if update.Variants != nil {
+			l.Variants = *update.Variants
+		}
+		if update.Rotation != nil {
+			l.Rotation = *update.Rotation
+		}
+		if update.Sticky != nil {
+			l.Sticky = *update.Sticky
+		}
+		if update.Password != nil {
+			l.PasswordHash = ""
+			if *update.Password != "" {
+				l.PasswordHash = hashPassword(*update.Password)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, newAPILink(code, l))
+}
+
+// VariantStats are the clicks of one variant of a split link
+type VariantStats struct {
+	Variant
+	Clicks int64 `json:"clicks"`
+}
+
+// VariantClicks pairs the variants of a link with their clicks
+func (l Link) VariantClicks(stats Stats) []VariantStats {
+	variants := []VariantStats{}
+	for i, v := range l.Variants {
+		vs := VariantStats{Variant: v}
+		if i < len(stats.Variants) {
+			vs.Clicks = stats.Variants[i]
+		}
+		variants = append(variants, vs)
+	}
+	return variants
+}
+
+// handleAPIStats reports the clicks of a link, broken down
+// by variant, together with the parameters it adds. Only the
+// API token sees the variants of links that hide them.
+func (s *Server) handleAPIStats(c *gin.Context) {
+	code := c.Param("code")
+	l, err := s.Lookup(code)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Could not find " + code})
+		return
+	}
+	stats := s.Stats(code)
+	variants := l.VariantClicks(stats)
+	if l.hidesDestinations() && !s.authorized(c.Request) {
+		for i := range variants {
+			variants[i].URL = ""
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":     code,
+		"stats":    stats,
+		"params":   l.Params,
+		"variants": variants,
+	})
+}
+
+// handleAPIRoute shows where a request with the given
+// headers would be sent by the routing rules of a link
+func (s *Server) handleAPIRoute(c *gin.Context) {
+	var test struct {
+		Headers map[string]string `json:"headers"`
+	}
+	if err := c.ShouldBindWith(&test, binding.JSON); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	code := c.Param("code")
+	l, err := s.Lookup(code)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Could not find " + code})
+		return
+	}
+	r, _ := http.NewRequest("GET", "/"+code, nil)
+	for key, value := range test.Headers {
+		r.Header.Set(key, value)
+	}
+	destination, rule := l.route(r)
+	c.JSON(http.StatusOK, gin.H{
+		"destination": destination,
+		"rule":        rule,
+		"platform":    platform(r.UserAgent()),
+	})
+}
+
+// handleAPISchedule shows which scheduled destination of a
+// link is active now and which one comes next
+func (s *Server) handleAPISchedule(c *gin.Context) {
+	code := c.Param("code")
+	l, err := s.Lookup(code)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Could not find " + code})
+		return
+	}
+	active, next := l.scheduled(now())
+	response := gin.H{
+		"code":     code,
+		"timezone": l.Timezone,
+		"schedule": l.Schedule,
+		"active":   nil,
+		"next":     nil,
+		"url":      l.URL,
+	}
+	if active >= 0 {
+		response["active"] = l.Schedule[active]
+		response["url"] = l.Schedule[active].URL
+	}
+	if next >= 0 {
+		response["next"] = l.Schedule[next]
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,285 @@
+// Code generated by go-bindata.
+// sources:
+// templates/index.html
+// templates/password.html
+// templates/stats.html
+// DO NOT EDIT!
+
+package urlss
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func bindataRead(data []byte, name string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewBuffer(data))
+	if err != nil {
+		return nil, fmt.Errorf("Read %q: %v", name, err)
+	}
+
+	var buf bytes.Buffer
+	_, err = io.Copy(&buf, gz)
+	clErr := gz.Close()
+
+	if err != nil {
+		return nil, fmt.Errorf("Read %q: %v", name, err)
+	}
+	if clErr != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+type asset struct {
+	bytes []byte
+	info  os.FileInfo
+}
+
+type bindataFileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (fi bindataFileInfo) Name() string {
+	return fi.name
+}
+func (fi bindataFileInfo) Size() int64 {
+	return fi.size
+}
+func (fi bindataFileInfo) Mode() os.FileMode {
+	return fi.mode
+}
+func (fi bindataFileInfo) ModTime() time.Time {
+	return fi.modTime
+}
+func (fi bindataFileInfo) IsDir() bool {
+	return false
+}
+func (fi bindataFileInfo) Sys() interface{} {
+	return nil
+}
+
+var _templatesIndexHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8c\x56\xef\x8e\xa3\x36\x10\xff\x9e\xa7\x98\x73\xdb\x53\x56\xbd\x40\xc8\xdd\x9e\xaa\x1c\x70\x52\x57\x55\x55\xa9\xaa\xd4\xeb\xf5\x01\x1c\x7b\x02\x56\x8d\x6d\xd9\x26\x4b\x8a\x78\xf7\xca\xc0\x26\xc0\x66\xb7\x27\xf3\x61\x3c\x33\x1e\xcf\x9f\xdf\x8c\x49\x4b\x5f\x49\x90\x54\x15\x19\x69\x5b\x88\x02\x05\x5d\x47\xf2\xd5\x2a\x2d\x91\xf2\x7c\x05\x00\x90\x56\xe8\x29\x28\x5a\x61\x46\x4e\x02\x1f\x8d\xb6\x9e\x00\xd3\xca\xa3\xf2\x19\x79\x14\xdc\x97\x19\xc7\x93\x60\xb8\xe9\x37\xef\x40\x28\xe1\x05\x95\x1b\xc7\xa8\xc4\x2c\x21\xa3\x21\xe7\xcf\x12\x07\x3a\xac\x83\xe6\x67\x68\x2f\xdb\xf0\x1d\xb5\xf2\x7b\x48\x3e\x98\x26\x4e\xa2\xdd\x3d\x56\xe0\xa8\x72\x1b\x87\x56\x1c\x3f\xcd\x34\x2b\x6a\x0b\xa1\xf6\xf0\x61\x6b\x1a\xa0\xb5\xd7\x4b\x71\x33\x38\xb3\x87\x8f\xf7\x5b\xd3\xcc\xa5\x52\x28\xdc\x94\x28\x8a\x32\xdc\x16\x7d\x9c\x4b\x83\x13\x1b\x27\xfe\xc5\x3d\x24\x3f\x2d\x8f\x32\x2d\xb5\xdd\xc3\x77\xc9\x21\xac\xb9\xcc\x50\xce\x85\x2a\xf6\xb0\x85\x64\x6b\x9a\x8b\xac\x5b\x5d\xc8\x32\x79\x77\xa5\x77\x13\xfa\x3d\xb4\xaf\xb9\xb8\xbb\x65\x8c\x2e\xce\x78\x6c\xfc\x86\x23\xd3\x96\x7a\xa1\xd5\x1e\x94\x56\x78\xeb\xa0\x50\xa6\xf6\xd7\xcb\x0f\xb5\xf7\x5a\x2d\x8c\x8d\xd9\x4b\xb6\xdb\x1f\xe6\x51\x1e\xb4\xe5\x68\xf7\x90\x98\xe6\x85\xf0\x3f\x98\xe6\xc5\x8c\xbe\xbf\x9f\x0a\xbb\xde\x72\x1a\x8f\xc8\x48\xe3\x01\x76\xab\x34\x60\x63\x44\x4d\x60\xa1\xbd\xc2\x26\xe5\xe2\x04\x4c\x52\xe7\x32\x22\x94\xb7\x7a\x84\xd7\xd3\x4a\xcb\x24\x6f\x5b\xf0\x23\x9e\xc9\x5f\xa5\xb6\x1e\x15\xfc\xfd\xe5\x77\x02\x5d\x97\xc6\x65\x32\x3f\xd0\xb6\x20\x8e\x10\xb9\x41\x0f\x39\x74\xdd\xc2\xe0\x2e\x4f\x29\x08\x9e\x91\x5e\x87\x40\x69\xf1\x38\xb4\x4c\xcf\xe8\x7b\x66\xba\x4b\x63\x9a\xa7\x71\xb9\x5b\x38\x36\xe6\xd9\x9f\x0d\x66\x64\xd8\x90\xde\x2c\xd3\xe6\x4c\x80\x53\x4f\x37\x4c\x1b\x81\x3c\x23\xd3\x08\x1e\x7a\xde\x9b\xe0\x3d\x81\x52\x70\x8e\x6a\x16\xe1\x43\x7f\x3c\x5c\x3b\x18\x5d\x5c\x6b\x82\xf7\x57\x97\x8d\xc5\xa3\x68\xa0\xeb\xe2\xb6\x9d\x47\x1d\x19\x55\x90\x3c\x15\x55\x01\xce\xb2\xff\xd5\x76\xa7\xe2\x73\x68\x93\x6c\xb7\xdd\x92\x01\x2f\x19\xe9\xe9\x01\xb7\xe3\x86\x4a\x3f\x0f\xe7\xcf\x2f\xc0\x34\xc7\x3e\x9c\x3c\xa4\x6a\x91\x25\xfb\xad\xfe\xfe\x48\x66\x69\x70\x9e\x7a\x47\x2e\xe9\x37\x73\xbb\x6d\x0b\x28\x1d\xf6\xa5\x46\x6b\xb5\xbd\x55\xe6\xb6\xbd\x0a\x9f\x17\xf0\xc9\xc4\xf2\xe0\xc1\x3e\xd7\x53\x7c\xa9\x75\xd4\xb6\x82\x0a\x7d\xa9\x79\x46\x8c\x76\x9e\x00\x65\xa1\x4f\x97\x61\x2e\xf0\x1c\xbe\xb4\xef\xd8\x1e\x2b\xb5\x95\x63\x0e\xc8\x38\x94\x6b\x2b\x09\x18\x49\x19\x96\x5a\x72\xb4\x19\xc1\x86\x56\x46\x62\xc4\x74\x45\xfa\xe1\x78\xd4\xac\x76\x10\xdf\x30\x7c\xb0\xdf\xcc\x9c\xa2\xd7\xd5\x87\x4a\xf8\x79\xfe\x7f\xd5\x6f\x5e\x41\x61\x1c\xe2\x5f\xf0\xcc\x53\x1f\x4b\x1d\x5e\x0a\xd7\xdb\xb3\x54\x15\x08\xd1\xc8\x82\xae\x1b\xfb\xf3\xa1\xb6\x16\x55\x68\xaf\x90\xae\x3f\x68\x15\x0a\x31\xa9\xc9\x14\x34\xdf\x4f\xd2\xf9\x39\x78\x97\x85\x33\x5f\x69\x11\x30\xd7\xab\x5d\x5f\xbd\x91\x9b\x4f\xac\x06\x00\x8d\x55\x1c\xae\x1b\x88\x19\xa6\xd2\x98\x8b\x53\xbe\xba\x39\x96\x98\x44\x6a\x49\x3e\x55\x49\xe3\xe9\x1c\x4b\x1d\xb3\xc2\xf8\xab\xb5\x13\xb5\x10\xa6\x00\x64\xc0\x35\xab\x2b\x54\x3e\x2a\xd0\xff\x22\x31\x90\x3f\x9f\x7f\xe3\x6b\x12\xe4\xe4\xee\x3a\x3c\xc5\x11\xd6\x81\x07\x6f\xdf\x82\xa2\x27\x51\x50\xaf\x6d\xc4\xa4\x30\x07\x4d\x2d\xbf\x5b\x0c\xf4\xa0\x1a\x0d\xd3\x03\x32\x38\x52\xe9\xf0\xd3\x73\x05\xad\x98\x14\xec\x9f\xa0\x51\xab\x1e\x9e\xeb\xa5\xa1\xb0\x6e\xdc\x17\x3d\x5a\xe1\xf1\x2b\x36\x7e\xfd\x62\x08\x3d\x72\xc9\x5d\x14\x2a\x70\x17\xf9\x12\xd5\xfa\xd5\x6b\x2e\x6e\x85\xa7\xed\x61\xf8\xe5\x80\xac\xcf\x54\x14\xc6\xa5\x43\x1f\x0d\x13\x73\x1e\x4a\x58\xdd\x24\x55\xe1\xeb\x6e\x3c\x3b\x63\x15\x56\x69\x3c\x3c\x38\xab\x34\x2e\x7d\x25\xf3\xd5\x7f\x03\x00\x69\xe7\x0c\x72\x1b\x09\x00\x00")
+
+func templatesIndexHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_templatesIndexHtml,
+		"templates/index.html",
+	)
+}
+
+func templatesIndexHtml() (*asset, error) {
+	bytes, err := templatesIndexHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "templates/index.html", size: 2331, mode: os.FileMode(438), modTime: time.Unix(1792411420, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+
+var _templatesPasswordHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8c\x53\x4d\x8f\xda\x30\x10\xbd\xe7\x57\x4c\x5d\x55\xda\x95\x16\x42\xd8\x0f\x55\x59\x87\x43\xa5\xaa\xea\xad\x7f\xc1\xc4\x03\xb1\xea\xd8\x96\x3d\xb0\xa1\x51\xfe\x7b\x65\x92\x25\x04\xd8\xaa\x72\x0e\x33\x7e\x6f\x5e\xc6\xe3\x67\x5e\x51\xad\x41\x0b\xb3\x2d\x58\xdb\xc2\x3c\x46\xd0\x75\x6c\x95\x24\xbc\x42\x21\x57\x09\x00\x00\xaf\x91\x04\x18\x51\x63\xc1\xf6\x0a\xdf\x9c\xf5\xc4\xa0\xb4\x86\xd0\x50\xc1\xde\x94\xa4\xaa\x90\xb8\x57\x25\xce\x8e\xc9\x03\x28\xa3\x48\x09\x3d\x0b\xa5\xd0\x58\x64\x6c\x10\x0a\x74\xd0\xd8\xc7\x71\xad\xad\x3c\x40\x7b\x4a\xe3\xb7\xb1\x86\x72\xc8\x9e\x5c\x93\x66\xf3\xe5\x33\xd6\x10\x84\x09\xb3\x80\x5e\x6d\x5e\x27\xcc\x5a\xf8\xad\x32\x39\x3c\x2d\x5c\x03\x62\x47\xf6\x12\x6e\xfa\x66\x72\x78\x79\x5e\xb8\x66\x8a\x6a\x65\x70\x56\xa1\xda\x56\xf1\x6f\xf3\x97\x29\x1a\x9b\x98\x05\xf5\x07\x73\xc8\xbe\x5e\x96\x96\x56\x5b\x9f\xc3\xe7\x6c\x1d\xd7\x14\x73\x42\x4a\x65\xb6\x39\x2c\x20\x5b\xb8\xe6\x84\x75\xc9\x29\xac\xb2\x87\x31\x5e\x9e\xc5\x8f\xd0\xfe\xab\xc5\xe5\x2d\x31\x71\x51\x43\xd8\xd0\x4c\x62\x69\xbd\x20\x65\x4d\x0e\xc6\x1a\xbc\x55\xa8\x8c\xdb\xd1\xf8\xf3\xf5\x8e\xc8\x9a\x0b\xb1\x61\x7a\xd9\x62\xf1\x65\x7a\xca\xb5\xf5\x12\x7d\x0e\x99\x6b\x3e\x38\xfe\x93\x6b\x3e\x9c\xe8\xe3\xf3\x39\xd8\x1d\x95\x79\x3a\x38\x83\xa7\xbd\xed\x12\x1e\xbd\x31\xb8\x26\x6e\xa1\x1f\x6d\xc3\xa5\xda\x43\xa9\x45\x08\x05\x53\x86\xbc\x1d\xec\xf5\xbe\x78\x95\xad\xda\x16\x68\xf0\x33\xfb\xe5\x2d\x61\x49\x28\xe3\x4c\x7f\x33\xe8\x3a\x9e\x56\xd9\xb4\xa6\x6d\x41\x6d\x60\x8e\xde\x5b\x0f\x5d\x77\xa1\xb7\x8c\x7a\x27\x90\xa7\xd5\xf2\xaa\x1a\x75\xc0\xab\xc2\xb5\xbf\xe6\x19\x79\x45\xdb\x58\x5f\x43\x8d\x54\x59\x59\x30\x67\x03\x5d\x1c\x28\x7e\xfc\x78\x65\xa0\x22\x43\x84\xf0\x66\xbd\x64\xc3\x9b\x1c\x73\x3a\xb8\x49\xee\xb4\x28\xb1\xb2\x5a\xa2\x2f\xd8\xf9\x48\x46\x4a\xd7\xb1\xe3\xeb\x29\x6d\xed\x34\x12\x16\xac\xdc\x79\x8f\x86\x66\x23\x27\xbd\xd1\xce\xda\xff\xf7\x66\x6f\xae\xbe\xb7\xb0\x5b\xd7\x8a\xd8\xe4\x7e\x7e\xd8\x4f\xb1\x0f\x9e\xf6\x36\x9c\x4a\xf0\x34\x4e\x67\xdc\xe3\xa9\x54\xfb\x55\x72\xd3\x0c\xa5\x46\xe1\xd9\xea\x9c\xc2\xd3\x73\xf7\xf0\x50\x7a\xe5\x68\x54\x93\xb6\xdc\xd5\x68\x68\xbe\x45\xfa\xae\x31\x86\xdf\x0e\x3f\xe5\xdd\x38\xa0\xfb\xf9\xc6\x96\xbb\x70\x77\xff\xfa\xee\xd4\x41\x22\xe1\x69\xef\xd1\x84\xa7\x15\xd5\x7a\x95\xfc\x1d\x00\x60\x59\x74\xe9\x4e\x05\x00\x00")
+
+func templatesPasswordHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_templatesPasswordHtml,
+		"templates/password.html",
+	)
+}
+
+func templatesPasswordHtml() (*asset, error) {
+	bytes, err := templatesPasswordHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "templates/password.html", size: 1358, mode: os.FileMode(438), modTime: time.Unix(1792411155, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+
+var _templatesStatsHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8c\x55\x5d\x6f\xeb\x36\x0c\x7d\xf7\xaf\x20\xb4\x5b\x60\xc3\xea\xaf\x34\x29\x06\x4f\xf6\x7d\x28\xb0\x62\xc0\x56\x6c\x6b\xb1\x87\xbd\x29\x96\x62\x0b\x93\x25\x43\x52\x52\x77\x86\xfe\xfb\x20\xdb\xf9\x70\x92\xf6\x16\xce\x03\x29\x9a\xe4\xe1\xf1\xa1\x82\x6b\xdb\x08\x10\x44\x56\x39\xea\x7b\x88\xbc\x05\xce\xa1\x22\x08\x70\xcd\x08\x2d\x02\x00\x00\xdc\x30\x4b\x40\x92\x86\xe5\x68\xc7\xd9\x6b\xab\xb4\x45\x50\x2a\x69\x99\xb4\x39\x7a\xe5\xd4\xd6\x39\x65\x3b\x5e\xb2\x70\x70\x6e\x81\x4b\x6e\x39\x11\xa1\x29\x89\x60\x79\x8a\xa6\x42\xc6\xbe\x09\x36\xda\xfe\x59\x2b\xfa\x06\xfd\xc1\xf5\xbf\x8d\x92\x36\x83\x74\xd9\x76\x71\x1a\x2d\x56\xac\x01\x43\xa4\x09\x0d\xd3\x7c\xf3\xf3\xec\xcd\x86\xe8\x8a\xcb\x0c\x96\x49\xdb\x01\xd9\x5a\x75\x1e\xee\x46\x30\x19\xdc\xaf\x92\xb6\x9b\x47\x05\x97\x2c\xac\x19\xaf\x6a\xdf\x2d\xba\x9f\x47\x3d\x88\xd0\xf0\xff\x58\x06\xe9\x4f\xe7\xa9\xa5\x12\x4a\x67\xf0\x5d\xba\xf6\xcf\x3c\xd6\x12\x4a\xb9\xac\x32\x48\x20\x4d\xda\xee\x10\x73\xc1\xc1\xac\xd3\xdb\xa3\xbd\x38\xb1\xef\xa0\xff\x08\xe2\xe2\x5a\x31\x72\x96\x63\x59\x67\x43\xca\x4a\xa5\x89\xe5\x4a\x66\x20\x95\x64\xd7\x12\xb9\x6c\xb7\xf6\xd8\x7c\xbd\xb5\x56\xc9\xb3\x62\x13\x7b\x69\x92\xdc\xcc\xa7\x5c\x2b\x4d\x99\xce\x20\x6d\xbb\x77\xc6\x5f\xb6\xdd\xbb\x8c\xde\xad\x4e\x83\x6e\xa8\x8c\xe3\x49\x19\x38\x1e\x65\x17\x60\xaf\x8d\x49\x35\xfe\x88\xe9\xa3\x6c\x30\xe5\x3b\x28\x05\x31\x26\x47\x5c\x5a\xad\x26\x79\xed\x1f\x5c\xa7\x05\x26\x50\x6b\xb6\x19\x65\xdd\x6a\xb6\xe1\x1d\x38\x17\x7b\xaf\x54\x94\x0d\x22\xf7\x8e\xa9\x95\xb6\xe0\x1c\x8e\x49\x81\xe3\x3a\x9d\x57\xea\x7b\xe0\x1b\x88\x28\x33\x96\xcb\x81\x52\x70\x6e\xde\xab\x2d\xfa\x1e\xec\xb4\x3a\xa8\x52\xcc\x80\x55\x08\x9c\x83\x19\x84\x79\x09\x54\x5c\x9e\x8d\x08\xda\x0b\x00\x4c\xd2\x6f\x34\xbd\xa1\x50\x0a\x5e\xfe\x6b\x10\x44\xc6\x12\x6b\xa2\x87\xc1\x05\xe7\xc6\x01\xa4\xb2\xfb\xc8\x6f\xc4\xd8\x21\x1a\xfd\x6a\xfe\x61\x5a\x81\x73\xb7\x70\x5a\x4c\x10\x63\x41\x49\xb8\x31\x08\xbe\xbf\x48\xfa\x45\xe9\x86\x58\x40\x8b\x24\xb9\x0f\x93\x34\x4c\x16\x90\xae\xb2\x64\x09\xbf\x3f\xbf\xa0\x1f\xc6\x86\x23\xe0\x8b\x51\x70\xfb\x89\x8f\x12\xb5\xb2\xfa\xea\x37\x2f\x4f\x93\xc5\x12\x15\x98\x37\x15\x18\x5d\x7e\x94\x62\x76\x53\xca\x5d\x92\xa0\x51\xb5\x39\x1a\xec\x71\x7b\x26\x87\x08\x9b\xa3\xd3\x49\xff\xfc\x0b\x7c\x09\xff\xad\x50\xe1\xd9\x9f\xc3\x5d\xeb\x19\xc9\x54\xbd\x4a\xa1\x08\x05\x62\x2e\xbf\xee\xa7\x66\xf9\xe3\xe9\xd1\x77\x99\xb1\xad\xf4\x67\x8b\x1d\xa6\x1c\x89\x79\xfe\xfb\xf1\xaa\x60\x70\xbb\xdf\x0c\xa1\xfc\xdd\x6b\x06\xa9\x69\x22\x2b\x06\xd1\x74\x74\xd0\x45\xf4\xb0\xd5\x9a\x49\xaf\x7f\xdf\xea\x89\x34\x7e\x31\x3c\x40\x26\x8c\x37\x4f\x71\x7d\x99\x03\xfb\xb2\x47\xf6\xe3\x57\x4f\x67\xee\x0b\xbc\x90\xca\x93\x39\xe4\x1c\xff\x54\xa6\xd3\xe2\xa4\xc5\x9e\x87\x51\x2a\xd7\x35\x83\x63\xca\x77\x45\x70\x75\xeb\x4b\xc1\x88\x46\xc5\xe9\x2b\xe3\xcd\xe1\xaf\x89\x00\xc7\xe3\xe5\x11\xe0\xb8\xb6\x8d\x28\x82\xff\x07\x00\x9d\x0f\x8a\x62\xe7\x06\x00\x00")
+
+func templatesStatsHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_templatesStatsHtml,
+		"templates/stats.html",
+	)
+}
+
+func templatesStatsHtml() (*asset, error) {
+	bytes, err := templatesStatsHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "templates/stats.html", size: 1767, mode: os.FileMode(438), modTime: time.Unix(1792412334, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+// Asset loads and returns the asset for the given name.
+// It returns an error if the asset could not be found or
+// could not be loaded.
+func Asset(name string) ([]byte, error) {
+	cannonicalName := strings.Replace(name, "\\", "/", -1)
+	if f, ok := _bindata[cannonicalName]; ok {
+		a, err := f()
+		if err != nil {
+			return nil, fmt.Errorf("Asset %s can't read by error: %v", name, err)
+		}
+		return a.bytes, nil
+	}
+	return nil, fmt.Errorf("Asset %s not found", name)
+}
+
+// MustAsset is like Asset but panics when Asset would return an error.
+// It simplifies safe initialization of global variables.
+func MustAsset(name string) []byte {
+	a, err := Asset(name)
+	if err != nil {
+		panic("asset: Asset(" + name + "): " + err.Error())
+	}
+
+	return a
+}
+
+// AssetInfo loads and returns the asset info for the given name.
+// It returns an error if the asset could not be found or
+// could not be loaded.
+func AssetInfo(name string) (os.FileInfo, error) {
+	cannonicalName := strings.Replace(name, "\\", "/", -1)
+	if f, ok := _bindata[cannonicalName]; ok {
+		a, err := f()
+		if err != nil {
+			return nil, fmt.Errorf("AssetInfo %s can't read by error: %v", name, err)
+		}
+		return a.info, nil
+	}
+	return nil, fmt.Errorf("AssetInfo %s not found", name)
+}
+
+// AssetNames returns the names of the assets.
+func AssetNames() []string {
+	names := make([]string, 0, len(_bindata))
+	for name := range _bindata {
+		names = append(names, name)
+	}
+	return names
+}
+
+// _bindata is a table, holding each asset generator, mapped to its name.
+var _bindata = map[string]func() (*asset, error){
+	"templates/index.html": templatesIndexHtml,
+	"templates/password.html": templatesPasswordHtml,
+	"templates/stats.html": templatesStatsHtml,
+}
+
+// AssetDir returns the file names below a certain
+// directory embedded in the file by go-bindata.
+// For example if you run go-bindata on data/... and data contains the
+// following hierarchy:
+//     data/
+//       foo.txt
+//       img/
+//         a.png
+//         b.png
+// then AssetDir("data") would return []string{"foo.txt", "img"}
+// AssetDir("data/img") would return []string{"a.png", "b.png"}
+// AssetDir("foo.txt") and AssetDir("notexist") would return an error
+// AssetDir("") will return []string{"data"}.
+func AssetDir(name string) ([]string, error) {
+	node := _bintree
+	if len(name) != 0 {
+		cannonicalName := strings.Replace(name, "\\", "/", -1)
+		pathList := strings.Split(cannonicalName, "/")
+		for _, p := range pathList {
+			node = node.Children[p]
+			if node == nil {
+				return nil, fmt.Errorf("Asset %s not found", name)
+			}
+		}
+	}
+	if node.Func != nil {
+		return nil, fmt.Errorf("Asset %s not found", name)
+	}
+	rv := make([]string, 0, len(node.Children))
+	for childName := range node.Children {
+		rv = append(rv, childName)
+	}
+	return rv, nil
+}
+
+type bintree struct {
+	Func     func() (*asset, error)
+	Children map[string]*bintree
+}
+var _bintree = &bintree{nil, map[string]*bintree{
+	"templates": &bintree{nil, map[string]*bintree{
+		"index.html": &bintree{templatesIndexHtml, map[string]*bintree{}},
+		"password.html": &bintree{templatesPasswordHtml, map[string]*bintree{}},
+		"stats.html": &bintree{templatesStatsHtml, map[string]*bintree{}},
+	}},
+}}
+
+// RestoreAsset restores an asset under the given directory
+func RestoreAsset(dir, name string) error {
+	data, err := Asset(name)
+	if err != nil {
+		return err
+	}
+	info, err := AssetInfo(name)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(_filePath(dir, filepath.Dir(name)), os.FileMode(0755))
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(_filePath(dir, name), data, info.Mode())
+	if err != nil {
+		return err
+	}
+	err = os.Chtimes(_filePath(dir, name), info.ModTime(), info.ModTime())
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// RestoreAssets restores an asset under the given directory recursively
+func RestoreAssets(dir, name string) error {
+	children, err := AssetDir(name)
+	// File
+	if err != nil {
+		return RestoreAsset(dir, name)
+	}
+	// Dir
+	for _, child := range children {
+		err = RestoreAssets(dir, filepath.Join(name, child))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func _filePath(dir, name string) string {
+	cannonicalName := strings.Replace(name, "\\", "/", -1)
+	return filepath.Join(append([]string{dir}, strings.Split(cannonicalName, "/")...)...)
+}
+
@@ -0,0 +1,391 @@
+package urlss
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Record is a link with its code, as links are imported and
+// exported. Unlike the API it may keep the hash of the password.
+type Record struct {
+	Code string `json:"code"`
+	Link
+	// Protected marks links exported without their password
+	// hash, which cannot be imported again
+	Protected bool `json:"protected,omitempty"`
+	// err is why a record read from an export cannot be imported
+	err error
+	// Clicks and Created carry the stats of the link
+	Clicks  int64      `json:"clicks,omitempty"`
+	Created *time.Time `json:"created,omitempty"`
+}
+
+// Formats are the formats links are imported and exported in: a
+// JSON array of records, one record per line, or CSV with a header
+var Formats = []string{"csv", "json", "jsonl"}
+
+// csvColumns are the columns of exported CSV files, named after the
+// JSON fields. Options that are not text are JSON in their cell.
+var csvColumns, csvText = recordColumns(reflect.TypeOf(Record{}), map[string]bool{})
+
+func recordColumns(t reflect.Type, text map[string]bool) ([]string, map[string]bool) {
+	var columns []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			embedded, _ := recordColumns(field.Type, text)
+			columns = append(columns, embedded...)
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		columns = append(columns, name)
+		text[name] = field.Type.Kind() == reflect.String || field.Type == reflect.TypeOf(&time.Time{})
+	}
+	return columns, text
+}
+
+// checkFormat reports formats that are not one of Formats
+func checkFormat(format string) error {
+	for _, f := range Formats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown format %s, use csv, json or jsonl", format)
+}
+
+// ReadRecords reads the records in r, which are in one of Formats
+// or ImportFormats. CSV files need a header with code and url,
+// which may be called destination, and may have any other column
+// of the export.
+func ReadRecords(r io.Reader, format string) (records []Record, err error) {
+	if read, ok := importers[format]; ok {
+		return read(r)
+	}
+	if checkFormat(format) != nil {
+		return nil, fmt.Errorf("Unknown format %s, use %s", format, strings.Join(append(append([]string{}, Formats...), ImportFormats...), ", "))
+	}
+	switch format {
+	case "json":
+		err = json.NewDecoder(r).Decode(&records)
+	case "jsonl":
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(nil, 1<<20)
+		for line := 1; scanner.Scan(); line++ {
+			if strings.TrimSpace(scanner.Text()) == "" {
+				continue
+			}
+			var record Record
+			if err = json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			records = append(records, record)
+		}
+		err = scanner.Err()
+	case "csv":
+		records, err = readCSV(r)
+	}
+	return
+}
+
+func readCSV(r io.Reader) (records []Record, err error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, errors.New("CSV files need a header with code and url")
+	}
+	hasURL := false
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if column == "destination" {
+			column = "url"
+		}
+		if _, ok := csvText[column]; !ok {
+			return nil, fmt.Errorf("Unknown column %s", header[i])
+		}
+		hasURL = hasURL || column == "url"
+		header[i] = column
+	}
+	if !hasURL {
+		return nil, errors.New("CSV files need a url column")
+	}
+	for line := 2; ; line++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		fields := map[string]json.RawMessage{}
+		for i, cell := range row {
+			if cell == "" {
+				continue
+			}
+			if csvText[header[i]] {
+				fields[header[i]], _ = json.Marshal(cell)
+			} else {
+				fields[header[i]] = json.RawMessage(cell)
+			}
+		}
+		data, err := json.Marshal(fields)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		var record Record
+		if err = json.Unmarshal(data, &record); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		records = append(records, record)
+	}
+}
+
+// ExportOptions shape Export
+type ExportOptions struct {
+	// PasswordHashes keeps the password hashes of protected
+	// links, which are left out otherwise
+	PasswordHashes bool `json:"password_hashes"`
+}
+
+// Export writes every link to w, one at a time
+func (s *Server) Export(w io.Writer, format string, opts ExportOptions) error {
+	if err := checkFormat(format); err != nil {
+		return err
+	}
+	var cw *csv.Writer
+	switch format {
+	case "csv":
+		cw = csv.NewWriter(w)
+		cw.Write(csvColumns)
+	case "json":
+		io.WriteString(w, "[\n")
+	}
+	first := true
+	for _, code := range s.Codes() {
+		l, err := s.Lookup(code)
+		if err != nil {
+			continue
+		}
+		record := Record{Code: code, Link: l}
+		if l.PasswordHash != "" && !opts.PasswordHashes {
+			record.PasswordHash = ""
+			record.Protected = true
+		}
+		stats := s.Stats(code)
+		record.Clicks = stats.Clicks
+		if !stats.Created.IsZero() {
+			record.Created = &stats.Created
+		}
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		switch format {
+		case "csv":
+			if err = cw.Write(csvRow(data)); err != nil {
+				return err
+			}
+		case "json":
+			if !first {
+				io.WriteString(w, ",\n")
+			}
+			_, err = w.Write(data)
+		default:
+			_, err = w.Write(append(data, '\n'))
+		}
+		if err != nil {
+			return err
+		}
+		first = false
+	}
+	switch format {
+	case "csv":
+		cw.Flush()
+		return cw.Error()
+	case "json":
+		_, err := io.WriteString(w, "\n]\n")
+		return err
+	}
+	return nil
+}
+
+// csvRow turns a record marshaled as JSON into a row of csvColumns
+func csvRow(data []byte) []string {
+	var fields map[string]json.RawMessage
+	json.Unmarshal(data, &fields)
+	row := make([]string, len(csvColumns))
+	for i, column := range csvColumns {
+		raw, ok := fields[column]
+		if !ok {
+			continue
+		}
+		if csvText[column] {
+			json.Unmarshal(raw, &row[i])
+		} else {
+			row[i] = string(raw)
+		}
+	}
+	return row
+}
+
+// ImportOptions decide what Import does
+type ImportOptions struct {
+	// Conflict is what happens to records whose code is taken:
+	// "skip" them (the default), "overwrite" the link, or
+	// "rename" them to a new code
+	Conflict string `json:"conflict"`
+	// DryRun reports what would happen without changing anything
+	DryRun bool `json:"dry_run"`
+}
+
+var conflictStrategies = map[string]bool{"": true, "skip": true, "overwrite": true, "rename": true}
+
+// ImportReport is what Import did, or would do in a dry run
+type ImportReport struct {
+	DryRun      bool `json:"dry_run"`
+	Created     int  `json:"created"`
+	Overwritten int  `json:"overwritten"`
+	Skipped     int  `json:"skipped"`
+	// Renamed maps the codes of the renamed records to their new codes
+	Renamed map[string]string `json:"renamed"`
+	Failed  []ImportError     `json:"failed"`
+}
+
+// ImportError is a record that could not be imported
+type ImportError struct {
+	// Record counts the records from 1
+	Record int    `json:"record"`
+	Code   string `json:"code"`
+	Error  string `json:"error"`
+}
+
+// Import adds records to the store in one change. Records
+// without a code get a new one, and invalid records are
+// reported without stopping the import.
+func (s *Server) Import(records []Record, opts ImportOptions) (report ImportReport, err error) {
+	if !conflictStrategies[opts.Conflict] {
+		err = errors.New("Unknown conflict strategy " + opts.Conflict + ", use skip, overwrite or rename")
+		return
+	}
+	report = ImportReport{DryRun: opts.DryRun, Renamed: map[string]string{}, Failed: []ImportError{}}
+	apply := func() error {
+		// planned are the codes a dry run would take
+		planned := map[string]bool{}
+		taken := func(code string) bool {
+			_, err := s.Lookup(code)
+			return err == nil || planned[code]
+		}
+		for i, record := range records {
+			existed := record.Code != "" && taken(record.Code)
+			code, err := s.importRecord(record, opts, taken)
+			if err != nil {
+				report.Failed = append(report.Failed, ImportError{i + 1, record.Code, err.Error()})
+				continue
+			}
+			switch {
+			case code == "":
+				report.Skipped++
+			case !existed:
+				report.Created++
+			case code == record.Code:
+				report.Overwritten++
+			default:
+				report.Renamed[record.Code] = code
+			}
+			if opts.DryRun {
+				planned[code] = true
+			}
+		}
+		return nil
+	}
+	if opts.DryRun {
+		s.refresh()
+		s.fileMu.Lock()
+		defer s.fileMu.Unlock()
+		err = apply()
+	} else {
+		err = s.update(apply)
+	}
+	if err == nil {
+		s.log.Info("Imported links", "created", report.Created, "overwritten", report.Overwritten, "renamed", len(report.Renamed), "skipped", report.Skipped, "failed", len(report.Failed))
+	}
+	return
+}
+
+// importRecord stores one record, unless it is a dry run, and
+// returns its code, which is "" if it was skipped
+func (s *Server) importRecord(record Record, opts ImportOptions, taken func(string) bool) (code string, err error) {
+	l := record.Link
+	if record.err != nil {
+		return "", record.err
+	}
+	if record.Protected && l.PasswordHash == "" {
+		return "", errors.New("Protected link without its password hash, export it with the hashes")
+	}
+	if l.URL == "" && len(l.Variants) > 0 {
+		l.URL = l.Variants[0].URL
+	}
+	if l.URL, err = s.policy.canonicalURL(strings.TrimSpace(l.URL)); err != nil {
+		return
+	}
+	if err = l.validate(s.policy); err != nil {
+		return
+	}
+	code = record.Code
+	switch {
+	case code == "":
+		code = s.newImportCode(taken)
+	case !isCode(code):
+		return "", errors.New("Invalid code " + code)
+	case s.reservedCode(code):
+		return "", errors.New("Code " + code + " is taken by the route /" + code + " of urlss")
+	case taken(code) && (opts.Conflict == "" || opts.Conflict == "skip"):
+		return "", nil
+	case taken(code) && opts.Conflict == "rename":
+		code = s.newImportCode(taken)
+	}
+	if code == "" {
+		return "", errors.New("No free code left")
+	}
+	if opts.DryRun {
+		return
+	}
+	if old, err := s.Lookup(code); err == nil {
+		var indexed string
+		if s.store.Get(old.URL, &indexed) == nil && indexed == code {
+			s.store.Delete(old.URL)
+		}
+	}
+	s.store.Set(code, l)
+	var indexed string
+	if l.plain() && s.store.Get(l.URL, &indexed) != nil {
+		s.store.Set(l.URL, code)
+	}
+	if record.Clicks > 0 || record.Created != nil {
+		stats := Stats{Clicks: record.Clicks}
+		if record.Created != nil {
+			stats.Created = record.Created.UTC()
+		}
+		s.store.Set(statsKey(code), stats)
+		s.clicks.take(code)
+	}
+	return
+}
+
+// newImportCode is a code that is neither stored nor
+// planned by a dry run
+func (s *Server) newImportCode(taken func(string) bool) string {
+	for i := 0; i < 10; i++ {
+		if code := s.NewCode(); code != "" && !taken(code) {
+			return code
+		}
+	}
+	return ""
+}
@@ -0,0 +1,143 @@
+package urlss
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReadCSV(t *testing.T) {
+	records, err := ReadRecords(strings.NewReader(`code,destination,params,prefix
+docs,https://docs.example.com/,,true
+,https://example.com/?a=1,"{""utm_source"":""csv""}",
+`), "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Code != "docs" || !records[0].Prefix || records[1].Params["utm_source"] != "csv" {
+		t.Errorf("Got %+v", records)
+	}
+	for _, bad := range []string{"code,target\nx,y\n", "code\nx\n", "code,url,prefix\nx,https://example.com,yes\n"} {
+		if _, err = ReadRecords(strings.NewReader(bad), "csv"); err == nil {
+			t.Errorf("%q should fail", bad)
+		}
+	}
+	if _, err = ReadRecords(strings.NewReader(""), "xml"); err == nil {
+		t.Error("Unknown formats should fail")
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.Shorten("https://example.com/plain")
+	s.AddLink(Link{URL: "https://example.com/options", Params: map[string]string{"utm_source": "x"}, Variants: []Variant{{"https://example.com/a", 1}, {"https://example.com/b", 2}}, PasswordHash: hashPassword("pw")})
+	for _, format := range Formats {
+		var buf bytes.Buffer
+		if err := s.Export(&buf, format, ExportOptions{PasswordHashes: true}); err != nil {
+			t.Fatal(err)
+		}
+		records, err := ReadRecords(&buf, format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		other := newTestServer(t, nil)
+		report, err := other.Import(records, ImportOptions{})
+		if err != nil || report.Created != 2 || len(report.Failed) != 0 {
+			t.Fatalf("%s: got %+v, %v", format, report, err)
+		}
+		for _, code := range s.Codes() {
+			l, _ := s.Lookup(code)
+			imported, err := other.Lookup(code)
+			if err != nil || !linksEqual(l, imported) {
+				t.Errorf("%s: %s became %+v", format, code, imported)
+			}
+		}
+		if code, _ := other.Shorten("https://example.com/plain"); code != s.Codes()[0] && code != s.Codes()[1] {
+			t.Errorf("%s: plain links should keep their code for their URL", format)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := s.Export(&buf, "jsonl", ExportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "pbkdf2") || !strings.Contains(buf.String(), `"protected":true`) {
+		t.Errorf("Password hashes should be left out, got %s", buf.String())
+	}
+	records, _ := ReadRecords(&buf, "jsonl")
+	report, err := newTestServer(t, nil).Import(records, ImportOptions{})
+	if err != nil || report.Created != 1 || len(report.Failed) != 1 {
+		t.Errorf("Protected links without their hash should fail, got %+v, %v", report, err)
+	}
+}
+
+func linksEqual(a, b Link) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return bytes.Equal(x, y)
+}
+
+func TestImportConflicts(t *testing.T) {
+	s := newTestServer(t, nil)
+	code, _ := s.Shorten("https://example.com/old")
+	records := []Record{
+		{Code: code, Link: Link{URL: "https://example.com/new"}},
+		{Code: "fresh", Link: Link{URL: "https://example.com/fresh"}},
+		{Code: "bad", Link: Link{URL: "javascript:alert(1)"}},
+		{Code: "no/slash", Link: Link{URL: "https://example.com"}},
+		{Code: "healthz", Link: Link{URL: "https://example.com/health"}},
+	}
+
+	report, err := s.Import(records, ImportOptions{Conflict: "overwrite", DryRun: true})
+	if err != nil || report.Overwritten != 1 || report.Created != 1 || len(report.Failed) != 3 || report.Failed[0].Record != 3 || !strings.Contains(report.Failed[2].Error, "route /healthz") {
+		t.Errorf("Got %+v, %v", report, err)
+	}
+	if _, err = s.Lookup("fresh"); err == nil {
+		t.Error("A dry run should not change links")
+	}
+
+	if report, _ = s.Import(records[:1], ImportOptions{}); report.Skipped != 1 {
+		t.Errorf("Got %+v", report)
+	}
+	report, _ = s.Import(records[:1], ImportOptions{Conflict: "rename"})
+	renamed := report.Renamed[code]
+	if l, err := s.Lookup(renamed); err != nil || renamed == code || l.URL != "https://example.com/new" {
+		t.Errorf("Got %+v", report)
+	}
+	report, _ = s.Import(records[:1], ImportOptions{Conflict: "overwrite"})
+	if l, _ := s.Lookup(code); report.Overwritten != 1 || l.URL != "https://example.com/new" {
+		t.Errorf("Got %+v", report)
+	}
+	if again, _ := s.Shorten("https://example.com/old"); again == code {
+		t.Error("The overwritten URL should no longer point to the code")
+	}
+	if _, err = s.Import(records, ImportOptions{Conflict: "merge"}); err == nil {
+		t.Error("Unknown conflict strategies should fail")
+	}
+}
+
+func TestAPIExportImport(t *testing.T) {
+	s := newTestServer(t, nil)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, apiRequest("POST", "/api/import?format=csv&dry_run=true", strings.NewReader("code,url\nabc,https://example.com\n")))
+	if w.Code != 200 || !strings.Contains(w.Body.String(), `"created":1`) || len(s.Codes()) != 0 {
+		t.Errorf("Got %d: %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, apiRequest("POST", "/api/import?format=csv", strings.NewReader("code,url\nabc,https://example.com\n")))
+	if w.Code != 200 || len(s.Codes()) != 1 {
+		t.Errorf("Got %d: %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, apiRequest("GET", "/api/export?format=csv", nil))
+	if w.Code != 200 || w.Header().Get("Content-Type") != "text/csv; charset=utf-8" || !strings.HasPrefix(w.Body.String(), "code,url,title,prefix,") || !strings.Contains(w.Body.String(), "\nabc,https://example.com,") {
+		t.Errorf("Got %d: %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, apiRequest("GET", "/api/export?format=xml", nil))
+	if w.Code != 400 {
+		t.Errorf("Got %d for an unknown format", w.Code)
+	}
+}
@@ -0,0 +1,250 @@
+package urlss
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config holds every setting of the server. It is read from the
+// YAML file given with -config, then from URLSS_* environment
+// variables named after the keys (URLSS_REDIRECT_WEB for
+// redirect.web), then from the flags.
+type Config struct {
+	// Data is the gzipped JSON file links are stored in
+	Data   string `yaml:"data"`
+	Listen string `yaml:"listen"`
+	// ShutdownTimeout is how long requests may take to
+	// finish once the server is told to stop
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// FlushInterval is how often the clicks counted in
+	// memory are saved, they are also saved at Close
+	FlushInterval time.Duration `yaml:"flush_interval"`
+	// BaseURL is shown in short links instead of the request host,
+	// it includes the Prefix
+	BaseURL string `yaml:"base_url"`
+	// Prefix is the path the server is mounted at, such as /s
+	Prefix string `yaml:"prefix"`
+	// Secret signs links and cookies, random at each start if empty
+	Secret string `yaml:"secret"`
+	// APIToken is the bearer token of the API routes that change
+	// or reveal links, which are refused if it is empty
+	APIToken string         `yaml:"api_token"`
+	Redirect RedirectConfig `yaml:"redirect"`
+	Codes    CodesConfig    `yaml:"codes"`
+	// Normalize and Strip are the -normalize and -strip lists
+	Normalize string         `yaml:"normalize"`
+	Strip     string         `yaml:"strip"`
+	Schemes   string         `yaml:"schemes"`
+	Templates string         `yaml:"templates"`
+	Static    string         `yaml:"static"`
+	Dev       bool           `yaml:"dev"`
+	Security  SecurityConfig `yaml:"security"`
+	// TrustProxy takes the client address from X-Forwarded-For
+	// and X-Real-IP, which only a proxy in front should set
+	TrustProxy bool      `yaml:"trust_proxy"`
+	Log        LogConfig `yaml:"log"`
+}
+
+// RedirectConfig are the statuses redirects are sent with
+type RedirectConfig struct {
+	// Web is for http, https and ftp, which browsers may cache
+	Web int `yaml:"web"`
+	// Other is for the handoff to apps, such as mailto: and tel:
+	Other int `yaml:"other"`
+}
+
+// CodesConfig shapes new short codes, which get longer when
+// the short ones run out
+type CodesConfig struct {
+	Alphabet  string `yaml:"alphabet"`
+	MinLength int    `yaml:"min_length"`
+	MaxLength int    `yaml:"max_length"`
+}
+
+// SecurityConfig is the policy of password protected links
+type SecurityConfig struct {
+	// PasswordAttempts wrong passwords from one address lock
+	// a link for that address for AttemptWindow
+	PasswordAttempts int           `yaml:"password_attempts"`
+	AttemptWindow    time.Duration `yaml:"attempt_window"`
+	// AccessDuration is how long a correct password is remembered
+	AccessDuration time.Duration `yaml:"access_duration"`
+}
+
+// DefaultConfig is the configuration without a file, environment or flags
+func DefaultConfig() Config {
+	return Config{
+		Data:            "urls.json.gz",
+		Listen:          ":8006",
+		ShutdownTimeout: 10 * time.Second,
+		FlushInterval:   10 * time.Second,
+		Redirect:        RedirectConfig{Web: 301, Other: 302},
+		Codes:           CodesConfig{Alphabet: "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ", MinLength: 1, MaxLength: 9},
+		Normalize:       "default",
+		Strip:           "utm_*,fbclid,gclid",
+		Schemes:         defaultSchemes,
+		Security:        SecurityConfig{PasswordAttempts: 5, AttemptWindow: 15 * time.Minute, AccessDuration: time.Hour},
+		Log:             LogConfig{Format: "logfmt", Level: "info"},
+	}
+}
+
+// LoadConfig reads a YAML file over cfg, unknown keys are errors
+func LoadConfig(cfg *Config, path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err = yaml.UnmarshalStrict(data, cfg); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+// envPrefix starts the environment variables of the settings
+const envPrefix = "URLSS_"
+
+// LoadEnv sets the fields of cfg that have an environment variable
+func LoadEnv(cfg *Config, getenv func(string) string) error {
+	return loadEnvValue(reflect.ValueOf(cfg).Elem(), envPrefix, getenv)
+}
+
+func loadEnvValue(v reflect.Value, prefix string, getenv func(string) string) error {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		name := prefix + strings.ToUpper(v.Type().Field(i).Tag.Get("yaml"))
+		if field.Kind() == reflect.Struct {
+			if err := loadEnvValue(field, name+"_", getenv); err != nil {
+				return err
+			}
+			continue
+		}
+		value := getenv(name)
+		if value == "" {
+			continue
+		}
+		var err error
+		switch field.Interface().(type) {
+		case string:
+			field.SetString(value)
+		case bool:
+			var b bool
+			b, err = strconv.ParseBool(value)
+			field.SetBool(b)
+		case time.Duration:
+			var d time.Duration
+			d, err = time.ParseDuration(value)
+			field.SetInt(int64(d))
+		case int:
+			var n int
+			n, err = strconv.Atoi(value)
+			field.SetInt(int64(n))
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// redirectStatuses can be used for redirects
+var redirectStatuses = map[int]bool{301: true, 302: true, 303: true, 307: true, 308: true}
+
+// validate checks the configuration, reporting every problem
+// at once, and returns the URL policy it sets
+func (cfg Config) validate() (p urlPolicy, err error) {
+	var problems []string
+	fail := func(key string, err error) {
+		problems = append(problems, key+": "+err.Error())
+	}
+
+	if _, err = parseBaseURL(cfg.BaseURL); err != nil {
+		fail("base_url", err)
+	}
+	if cfg.Prefix != "" && (cfg.Prefix[0] != '/' || strings.ContainsAny(cfg.Prefix, "?#")) {
+		fail("prefix", errors.New("must be a path such as /s"))
+	}
+	if cfg.Data == "" {
+		fail("data", errors.New("needs a file name"))
+	}
+	if cfg.Listen == "" {
+		fail("listen", errors.New("needs an address"))
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		fail("shutdown_timeout", errors.New("must be positive"))
+	}
+	if cfg.FlushInterval <= 0 {
+		fail("flush_interval", errors.New("must be positive"))
+	}
+	if !redirectStatuses[cfg.Redirect.Web] {
+		fail("redirect.web", fmt.Errorf("%d is not a redirect status", cfg.Redirect.Web))
+	}
+	if !redirectStatuses[cfg.Redirect.Other] {
+		fail("redirect.other", fmt.Errorf("%d is not a redirect status", cfg.Redirect.Other))
+	}
+	if err = cfg.Codes.validate(); err != nil {
+		fail("codes", err)
+	}
+	if p.normalize, err = parseNormalizePolicy(cfg.Normalize, cfg.Strip); err != nil {
+		fail("normalize", err)
+	}
+	if p.schemes, err = parseSchemes(cfg.Schemes); err != nil {
+		fail("schemes", err)
+	}
+	if err = checkDir("templates", cfg.Templates); err != nil {
+		fail("templates", err)
+	} else if !cfg.Dev {
+		// dev mode shows broken templates on their pages instead
+		if _, err = loadTemplates(cfg.Templates, templateNames...); err != nil {
+			fail("templates", err)
+		}
+	}
+	if err = checkDir("static", cfg.Static); err != nil {
+		fail("static", err)
+	}
+	if cfg.Security.PasswordAttempts < 1 {
+		fail("security.password_attempts", errors.New("must be at least 1"))
+	}
+	if cfg.Security.AttemptWindow <= 0 || cfg.Security.AccessDuration <= 0 {
+		fail("security", errors.New("durations must be positive"))
+	}
+	if !logFormats[cfg.Log.Format] {
+		fail("log.format", errors.New("must be logfmt or json"))
+	}
+	if _, ok := logLevels[cfg.Log.Level]; !ok {
+		fail("log.level", errors.New("must be debug, info, warn or error"))
+	}
+	if len(problems) > 0 {
+		return p, errors.New("invalid configuration:\n  " + strings.Join(problems, "\n  "))
+	}
+	return p, nil
+}
+
+// Validate checks the configuration, reporting every problem at once
+func (cfg Config) Validate() error {
+	_, err := cfg.validate()
+	return err
+}
+
+// validate checks that codes made from the alphabet are
+// codes, RandString draws from at most 64 characters
+func (c CodesConfig) validate() error {
+	if len(c.Alphabet) < 2 || len(c.Alphabet) > 1<<letterIdxBits || !isCode(c.Alphabet) {
+		return errors.New("the alphabet needs 2 to 64 of A-Z, a-z, 0-9, - and _")
+	}
+	for i := range c.Alphabet {
+		if strings.IndexByte(c.Alphabet[i+1:], c.Alphabet[i]) >= 0 {
+			return errors.New("the alphabet repeats " + c.Alphabet[i:i+1])
+		}
+	}
+	if c.MinLength < 1 || c.MaxLength < c.MinLength {
+		return errors.New("lengths need 1 <= min_length <= max_length")
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package urlss
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/schollz/jsonstore"
+)
+
+func TestConfig(t *testing.T) {
+	f, err := ioutil.TempFile("", "urlss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`
+listen: 127.0.0.1:9000
+base_url: https://urls.example.com/
+redirect:
+  web: 308
+codes:
+  alphabet: abc123
+  min_length: 4
+security:
+  attempt_window: 1h
+`)
+	f.Close()
+
+	cfg := DefaultConfig()
+	if err = LoadConfig(&cfg, f.Name()); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{
+		"URLSS_LISTEN":                     ":9001",
+		"URLSS_SECURITY_PASSWORD_ATTEMPTS": "3",
+		"URLSS_DEV":                        "true",
+		"URLSS_LOG_FORMAT":                 "json",
+	}
+	if err = LoadEnv(&cfg, func(name string) string { return env[name] }); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Listen != ":9001" || cfg.Redirect.Web != 308 || cfg.Redirect.Other != 302 ||
+		cfg.Codes.Alphabet != "abc123" || cfg.Codes.MaxLength != 9 || !cfg.Dev ||
+		cfg.Security.PasswordAttempts != 3 || cfg.Security.AttemptWindow != time.Hour || cfg.Log.Format != "json" {
+		t.Errorf("Got %+v", cfg)
+	}
+
+	cfg.Data = filepath.Join(os.TempDir(), "urlss-config-test.json.gz")
+	s, err := NewServer(cfg, new(jsonstore.JSONStore), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.base != "https://urls.example.com" || s.redirectCode("https://example.com", false) != 308 {
+		t.Error("The configuration was not applied")
+	}
+	if code := s.NewCode(); len(code) != 4 || strings.Trim(code, "abc123") != "" {
+		t.Errorf("Got code %s", code)
+	}
+
+	env["URLSS_SECURITY_ACCESS_DURATION"] = "soon"
+	if err = LoadEnv(&cfg, func(name string) string { return env[name] }); err == nil || !strings.Contains(err.Error(), "URLSS_SECURITY_ACCESS_DURATION") {
+		t.Errorf("Got %v", err)
+	}
+
+	ioutil.WriteFile(f.Name(), []byte("prot: 8006\n"), 0644)
+	if err = LoadConfig(&cfg, f.Name()); err == nil {
+		t.Error("Unknown keys should fail")
+	}
+}
+
+func TestConfigValidation(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Redirect.Other = 200
+	cfg.Codes.Alphabet = "ab~"
+	cfg.Schemes = "http,javascript"
+	cfg.Log.Level = "verbose"
+	_, err := NewServer(cfg, new(jsonstore.JSONStore), nil)
+	if err == nil {
+		t.Fatal("Should fail")
+	}
+	for _, key := range []string{"redirect.other", "codes", "schemes", "log.level"} {
+		if !strings.Contains(err.Error(), key+":") {
+			t.Errorf("%s is not reported in %s", key, err)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package urlss
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The errors of a Server are one of these, as told by errors.Is
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrInvalidURL   = errors.New("invalid URL")
+	ErrBlocked      = errors.New("blocked")
+	ErrExpired      = errors.New("expired")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrUnavailable  = errors.New("unavailable")
+)
+
+// statusKinds are the exported errors of the statuses
+var statusKinds = map[int]error{
+	http.StatusNotFound:           ErrNotFound,
+	http.StatusBadRequest:         ErrInvalidURL,
+	http.StatusForbidden:          ErrBlocked,
+	http.StatusGone:               ErrExpired,
+	http.StatusTooManyRequests:    ErrRateLimited,
+	http.StatusUnauthorized:       ErrUnauthorized,
+	http.StatusServiceUnavailable: ErrUnavailable,
+}
+
+// statusError is an error answered with its own HTTP status,
+// errors without one are bad requests
+type statusError struct {
+	status int
+	err    error
+}
+
+func (e *statusError) Error() string {
+	return e.err.Error()
+}
+
+func (e *statusError) Unwrap() error {
+	return e.err
+}
+
+// Is tells the kind of the error by its status
+func (e *statusError) Is(target error) bool {
+	return statusKinds[e.status] == target
+}
+
+// notFoundError is for codes that do not exist, or that
+// should not be revealed
+func notFoundError(target string) error {
+	return &statusError{http.StatusNotFound, errorf("Could not find %s", target)}
+}
+
+// invalidURLError is for URLs that can not be shortened
+func invalidURLError(err error) error {
+	return &statusError{http.StatusBadRequest, err}
+}
+
+// blockedError is for URLs and tokens that are refused
+func blockedError(err error) error {
+	return &statusError{http.StatusForbidden, err}
+}
+
+// expiredError is for links that worked until some time
+func expiredError(err error) error {
+	return &statusError{http.StatusGone, err}
+}
+
+// rateLimitedError is for clients that tried too often
+func rateLimitedError(err error) error {
+	return &statusError{http.StatusTooManyRequests, err}
+}
+
+// errorStatus is the HTTP status a request that failed with err gets
+func errorStatus(err error) int {
+	var e *statusError
+	if errors.As(err, &e) {
+		return e.status
+	}
+	return http.StatusBadRequest
+}
+
+// wantsJSON reports whether the client prefers JSON to HTML
+func wantsJSON(c *gin.Context) bool {
+	return c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON
+}
+
+// renderError answers a failed request with the status of err, as
+// {"error": ...} to JSON clients and as the front page otherwise
+func renderError(c *gin.Context, err error) {
+	status := errorStatus(err)
+	if wantsJSON(c) {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	renderPage(c, status, "index.html", gin.H{
+		"error": translateError(locale(c), err),
+	})
+}
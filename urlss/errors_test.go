@@ -0,0 +1,74 @@
+package urlss
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestErrorStatus(t *testing.T) {
+	s := newTestServer(t, nil)
+	code, err := s.Shorten("https://example.com/errors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, status := range map[string]int{
+		"/" + code:                    301,
+		"/doesnotexist2":              404,
+		"/doesnotexist2+":             404,
+		"/doesnotexist2.png":          404,
+		"/?url=javascript:alert(1)":   403,
+		"/?url=http://exa%20mple.com": 400,
+		"/" + s.signLink(code, time.Now().Add(-time.Hour)): 410,
+		"/~" + code + ".1.forged":                          403,
+	} {
+		for _, accept := range []string{"text/html", "application/json"} {
+			req := httptest.NewRequest("GET", path, nil)
+			req.Header.Set("Accept", accept)
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, req)
+			if w.Code != status {
+				t.Errorf("%s as %s: got %d instead of %d", path, accept, w.Code, status)
+			}
+			if status == 301 {
+				continue
+			}
+			if accept == "text/html" && !strings.Contains(w.Body.String(), "<html") {
+				t.Errorf("%s: HTML clients should get a page", path)
+			}
+			var body struct {
+				Error string `json:"error"`
+			}
+			if accept == "application/json" && (json.Unmarshal(w.Body.Bytes(), &body) != nil || body.Error == "") {
+				t.Errorf("%s: JSON clients should get an error, got %s", path, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	s := newTestServer(t, nil)
+	code, _ := s.Shorten("https://example.com/kinds")
+	_, notFound := s.Lookup("doesnotexist3")
+	_, blocked := s.Shorten("javascript:alert(1)")
+	_, invalid := s.Shorten("http://exa mple.com")
+	_, _, _, expired := s.resolveCode(s.signLink(code, time.Now().Add(-time.Hour)))
+	for err, kind := range map[error]error{
+		notFound:           ErrNotFound,
+		blocked:            ErrBlocked,
+		invalid:            ErrInvalidURL,
+		expired:            ErrExpired,
+		errTooManyAttempts: ErrRateLimited,
+		errClosed:          ErrUnavailable,
+	} {
+		if !errors.Is(err, kind) {
+			t.Errorf("%v should be %v", err, kind)
+		}
+		if errors.Is(err, ErrNotFound) != (kind == ErrNotFound) {
+			t.Errorf("%v should only be %v", err, kind)
+		}
+	}
+}
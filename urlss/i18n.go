@@ -0,0 +1,215 @@
+package urlss
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/message/catalog"
+)
+
+// locales are the languages of the web pages, English is the
+// default and the key of every message
+var locales = []language.Tag{language.English, language.German, language.French}
+
+// localeNames label the language switcher
+var localeNames = map[language.Tag]string{
+	language.English: "English",
+	language.German:  "Deutsch",
+	language.French:  "Français",
+}
+
+var localeMatcher = language.NewMatcher(locales)
+
+// langCookie remembers the language picked with ?lang=
+const langCookie = "urlss_lang"
+
+// translations are the catalogs of the other locales, keyed
+// by the English message or format
+var translations = map[language.Tag]map[string]string{
+	language.German: {
+		"Shorten URL":                       "URL kürzen",
+		"Go!":                               "Los!",
+		"Copy":                              "Kopieren",
+		"Copied!":                           "Kopiert!",
+		"stats":                             "Statistik",
+		"QR code":                           "QR-Code",
+		"Protected link":                    "Geschützter Link",
+		"password":                          "Passwort",
+		"goes to":                           "führt zu",
+		"%d clicks":                         "%d Klicks",
+		"last on %s":                        "zuletzt am %s",
+		"download as":                       "herunterladen als",
+		"or":                                "oder",
+		"No URL given":                      "Keine URL angegeben",
+		"Could not find %s":                 "%s wurde nicht gefunden",
+		"Invalid URL %s":                    "Ungültige URL %s",
+		"Invalid URL %s: %v":                "Ungültige URL %s: %v",
+		"%s link is empty":                  "%s-Link ist leer",
+		"%s needs a phone number":           "%s braucht eine Telefonnummer",
+		"Scheme %s is not allowed":          "Das Schema %s ist nicht erlaubt",
+		"mailto needs an email address":     "mailto braucht eine E-Mail-Adresse",
+		"magnet needs an xt=urn: parameter": "magnet braucht einen xt=urn:-Parameter",
+		"ssh needs a host":                  "ssh braucht einen Host",
+		"This link needs a password":        "Dieser Link braucht ein Passwort",
+		"This link is invalid":              "Dieser Link ist ungültig",
+		"This link has expired":             "Dieser Link ist abgelaufen",
+		"Wrong password":                    "Falsches Passwort",
+		"Too many wrong passwords, try again later": "Zu viele falsche Passwörter, versuche es später noch einmal",
+	},
+	language.French: {
+		"Shorten URL":                       "Raccourcir une URL",
+		"Go!":                               "Go !",
+		"Copy":                              "Copier",
+		"Copied!":                           "Copié !",
+		"stats":                             "statistiques",
+		"QR code":                           "Code QR",
+		"Protected link":                    "Lien protégé",
+		"password":                          "mot de passe",
+		"goes to":                           "mène à",
+		"%d clicks":                         "%d clics",
+		"last on %s":                        "le dernier le %s",
+		"download as":                       "télécharger en",
+		"or":                                "ou",
+		"No URL given":                      "Aucune URL donnée",
+		"Could not find %s":                 "%s est introuvable",
+		"Invalid URL %s":                    "URL invalide %s",
+		"Invalid URL %s: %v":                "URL invalide %s : %v",
+		"%s link is empty":                  "Le lien %s est vide",
+		"%s needs a phone number":           "%s a besoin d'un numéro de téléphone",
+		"Scheme %s is not allowed":          "Le schéma %s n'est pas autorisé",
+		"mailto needs an email address":     "mailto a besoin d'une adresse e-mail",
+		"magnet needs an xt=urn: parameter": "magnet a besoin d'un paramètre xt=urn:",
+		"ssh needs a host":                  "ssh a besoin d'un hôte",
+		"This link needs a password":        "Ce lien demande un mot de passe",
+		"This link is invalid":              "Ce lien est invalide",
+		"This link has expired":             "Ce lien a expiré",
+		"Wrong password":                    "Mot de passe incorrect",
+		"Too many wrong passwords, try again later": "Trop de mots de passe incorrects, réessayez plus tard",
+	},
+}
+
+var messages = catalog.New()
+
+func init() {
+	for tag, msgs := range translations {
+		for key, msg := range msgs {
+			messages.SetString(tag, key, msg)
+		}
+	}
+}
+
+// uiError is an error shown on the web pages, its format is
+// the key of its translations
+type uiError struct {
+	format string
+	args   []interface{}
+}
+
+func (e *uiError) Error() string {
+	return fmt.Sprintf(e.format, e.args...)
+}
+
+// errorf is fmt.Errorf for errors that can be translated
+func errorf(format string, args ...interface{}) error {
+	return &uiError{format, args}
+}
+
+// translate returns a message in a language, messages
+// without a translation stay in English
+func translate(lang language.Tag, key string, args ...interface{}) string {
+	return message.NewPrinter(lang, message.Catalog(messages)).Sprintf(key, args...)
+}
+
+// translateError translates the messages of uiErrors and of
+// errors whose whole message is in the catalogs
+func translateError(lang language.Tag, err error) string {
+	if e, ok := err.(*statusError); ok {
+		err = e.err
+	}
+	if e, ok := err.(*uiError); ok {
+		return translate(lang, e.format, e.args...)
+	}
+	return translate(lang, strings.Replace(err.Error(), "%", "%%", -1))
+}
+
+// templateFuncs are available in every template, {{ t .lang "Go!" }}
+// translates a message into the language of the page
+var templateFuncs = template.FuncMap{
+	"t": func(lang language.Tag, key string, args ...interface{}) string {
+		return translate(lang, key, args...)
+	},
+}
+
+// locale picks the language of a request from ?lang=, which is
+// remembered in a cookie, then the cookie, then Accept-Language
+func locale(c *gin.Context) language.Tag {
+	if lang, ok := c.Get(langCookie); ok {
+		return lang.(language.Tag)
+	}
+	lang := requestLocale(c)
+	c.Set(langCookie, lang)
+	return lang
+}
+
+func requestLocale(c *gin.Context) language.Tag {
+	if lang, ok := supportedLocale(c.Query("lang")); ok {
+		http.SetCookie(c.Writer, &http.Cookie{
+			Name:    langCookie,
+			Value:   lang.String(),
+			Path:    c.GetString(prefixKey) + "/",
+			Expires: time.Now().Add(365 * 24 * time.Hour),
+		})
+		return lang
+	}
+	if cookie, err := c.Request.Cookie(langCookie); err == nil {
+		if lang, ok := supportedLocale(cookie.Value); ok {
+			return lang
+		}
+	}
+	tags, _, _ := language.ParseAcceptLanguage(c.GetHeader("Accept-Language"))
+	_, index, _ := localeMatcher.Match(tags...)
+	return locales[index]
+}
+
+// supportedLocale returns the locale named exactly by tag
+func supportedLocale(tag string) (language.Tag, bool) {
+	if tag == "" {
+		return language.Und, false
+	}
+	t, err := language.Parse(tag)
+	if err != nil {
+		return language.Und, false
+	}
+	for _, l := range locales {
+		if l == t {
+			return l, true
+		}
+	}
+	return language.Und, false
+}
+
+// localeLink is an entry of the language switcher
+type localeLink struct {
+	Tag     language.Tag
+	Name    string
+	Current bool
+}
+
+// renderPage renders an HTML page in the language of the request
+func renderPage(c *gin.Context, status int, name string, data gin.H) {
+	lang := locale(c)
+	links := make([]localeLink, len(locales))
+	for i, l := range locales {
+		links[i] = localeLink{l, localeNames[l], l == lang}
+	}
+	data["lang"] = lang
+	data["prefix"] = c.GetString(prefixKey)
+	data["locales"] = links
+	c.HTML(status, name, data)
+}
@@ -0,0 +1,92 @@
+package urlss
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLocales(t *testing.T) {
+	s := newTestServer(t, nil)
+	get := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w
+	}
+
+	for acceptLanguage, want := range map[string]string{
+		"":                  "Shorten URL",
+		"de-CH,de;q=0.9":    "URL kürzen",
+		"ja,fr;q=0.5":       "Raccourcir une URL",
+		"ja":                "Shorten URL",
+		"en-GB,de;q=0.9":    "Shorten URL",
+		"not a header;;q=x": "Shorten URL",
+	} {
+		w := get("/", map[string]string{"Accept-Language": acceptLanguage})
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("%q: missing %q", acceptLanguage, want)
+		}
+	}
+
+	w := get("/?lang=fr", map[string]string{"Accept-Language": "de"})
+	if !strings.Contains(w.Body.String(), `<html lang="fr">`) {
+		t.Error("?lang= should win over Accept-Language")
+	}
+	cookie := w.Header().Get("Set-Cookie")
+	if !strings.HasPrefix(cookie, langCookie+"=fr") {
+		t.Fatalf("Got cookie %s", cookie)
+	}
+	w = get("/", map[string]string{"Accept-Language": "de", "Cookie": langCookie + "=fr"})
+	if !strings.Contains(w.Body.String(), "Raccourcir une URL") {
+		t.Error("The cookie should win over Accept-Language")
+	}
+
+	w = get("/?url=javascript:alert(1)", map[string]string{"Accept-Language": "de"})
+	if !strings.Contains(w.Body.String(), "Das Schema javascript ist nicht erlaubt") {
+		t.Errorf("Errors should be translated: %s", w.Body.String())
+	}
+	w = get("/doesnotexist1+", map[string]string{"Accept-Language": "fr"})
+	if !strings.Contains(w.Body.String(), "doesnotexist1 est introuvable") {
+		t.Errorf("Errors should be translated: %s", w.Body.String())
+	}
+}
+
+func TestLocaleLinks(t *testing.T) {
+	s := newTestServer(t, func(cfg *Config) { cfg.Prefix = "/s" })
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/s/example.com/page", nil))
+	if w.Code != 200 || !strings.Contains(w.Body.String(), `href="/s/?lang=de"`) || strings.Contains(w.Body.String(), `href="?lang=`) {
+		t.Errorf("The switcher should link to the front page, got %s", w.Body.String())
+	}
+	code := s.Codes()[0]
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/s/"+code+"+", nil))
+	if !strings.Contains(w.Body.String(), `href="/s/`+code+`+?lang=fr"`) {
+		t.Errorf("The switcher should link to the stats page, got %s", w.Body.String())
+	}
+}
+
+func TestCatalogs(t *testing.T) {
+	// every locale translates the same messages
+	for tag, msgs := range translations {
+		for other, otherMsgs := range translations {
+			for key := range msgs {
+				if _, ok := otherMsgs[key]; !ok {
+					t.Errorf("%s is missing %q of %s", other, key, tag)
+				}
+			}
+		}
+		for key, msg := range msgs {
+			if strings.Count(key, "%") != strings.Count(msg, "%") {
+				t.Errorf("%s: %q and %q have different verbs", tag, key, msg)
+			}
+		}
+	}
+	if got := translateError(locales[1], errorf("Could not find %s", "100%")); got != "100% wurde nicht gefunden" {
+		t.Errorf("Got %s", got)
+	}
+}
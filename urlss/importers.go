@@ -0,0 +1,253 @@
+package urlss
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ImportFormats are the exports of other shorteners that
+// ReadRecords understands, keeping their codes:
+//
+//	yourls  an SQL dump of the YOURLS url table
+//	shlink  the CSV export of Shlink
+//	polr    the CSV export of Polr, without its disabled links
+var ImportFormats = []string{"yourls", "shlink", "polr"}
+
+var importers = map[string]func(io.Reader) ([]Record, error){
+	"yourls": readYOURLS,
+	"shlink": readShlink,
+	"polr":   readPolr,
+}
+
+// yourlsColumns are the columns of the YOURLS url table,
+// for inserts that do not name them
+var yourlsColumns = []string{"keyword", "url", "title", "timestamp", "ip", "clicks"}
+
+// yourlsInsert starts the inserts into the url table, whatever
+// the prefix of the tables, up to their values
+var yourlsInsert = regexp.MustCompile("(?i)INSERT\\s+INTO\\s+`?\\w*url`?\\s*(?:\\(([^)]*)\\))?\\s*VALUES\\s*")
+
+// readYOURLS reads the links inserted into the url table of
+// a YOURLS database dump, as mysqldump writes it
+func readYOURLS(r io.Reader) (records []Record, err error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return
+	}
+	dump := string(data)
+	for {
+		loc := yourlsInsert.FindStringSubmatchIndex(dump)
+		if loc == nil {
+			break
+		}
+		columns := yourlsColumns
+		if loc[2] >= 0 {
+			columns = strings.Split(strings.NewReplacer("`", "", " ", "").Replace(dump[loc[2]:loc[3]]), ",")
+		}
+		var rows [][]string
+		rows, dump, err = sqlValues(dump[loc[1]:])
+		if err != nil {
+			return nil, err
+		}
+		for _, values := range rows {
+			if len(values) != len(columns) {
+				return nil, fmt.Errorf("Expected %d values, got %d", len(columns), len(values))
+			}
+			row := map[string]string{}
+			for i, column := range columns {
+				row[strings.ToLower(column)] = values[i]
+			}
+			record, err := newImportRecord(row["keyword"], row["url"], row["title"], row["clicks"], row["timestamp"])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", row["keyword"], err)
+			}
+			records = append(records, record)
+		}
+	}
+	if records == nil {
+		return nil, errors.New("Found no inserts into the url table of YOURLS")
+	}
+	return
+}
+
+// sqlValues parses the tuples of an insert, such as
+// ('a','b',1),('c',NULL,2); and returns the SQL after it
+func sqlValues(sql string) (rows [][]string, rest string, err error) {
+	for {
+		sql = strings.TrimLeft(sql, " \t\r\n")
+		if !strings.HasPrefix(sql, "(") {
+			return nil, "", errors.New("Expected ( in the values of an insert")
+		}
+		sql = sql[1:]
+		var row []string
+		for {
+			var value string
+			sql = strings.TrimLeft(sql, " \t\r\n")
+			if value, sql, err = sqlValue(sql); err != nil {
+				return
+			}
+			row = append(row, value)
+			sql = strings.TrimLeft(sql, " \t\r\n")
+			if strings.HasPrefix(sql, ")") {
+				break
+			}
+			if !strings.HasPrefix(sql, ",") {
+				return nil, "", errors.New("Expected , or ) in the values of an insert")
+			}
+			sql = sql[1:]
+		}
+		rows = append(rows, row)
+		sql = strings.TrimLeft(sql[1:], " \t\r\n")
+		if !strings.HasPrefix(sql, ",") {
+			return rows, sql, nil
+		}
+		sql = sql[1:]
+	}
+}
+
+// sqlEscapes are the backslash escapes of MySQL strings
+var sqlEscapes = map[byte]string{'0': "\x00", 'n': "\n", 'r': "\r", 't': "\t", 'Z': "\x1a"}
+
+// sqlValue parses one value of a tuple, NULL is ""
+func sqlValue(sql string) (value, rest string, err error) {
+	if !strings.HasPrefix(sql, "'") {
+		end := strings.IndexAny(sql, ",)")
+		if end < 0 {
+			return "", "", errors.New("Unterminated values of an insert")
+		}
+		value = strings.TrimSpace(sql[:end])
+		if strings.EqualFold(value, "NULL") {
+			value = ""
+		}
+		return value, sql[end:], nil
+	}
+	var b bytes.Buffer
+	for i := 1; i < len(sql); i++ {
+		switch c := sql[i]; {
+		case c == '\\' && i+1 < len(sql):
+			i++
+			if escaped, ok := sqlEscapes[sql[i]]; ok {
+				b.WriteString(escaped)
+			} else {
+				b.WriteByte(sql[i])
+			}
+		case c == '\'' && i+1 < len(sql) && sql[i+1] == '\'':
+			i++
+			b.WriteByte('\'')
+		case c == '\'':
+			return b.String(), sql[i+1:], nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", errors.New("Unterminated string in the values of an insert")
+}
+
+// readShlink reads the CSV export of Shlink, whose codes
+// are in shortCode or at the end of shortUrl
+func readShlink(r io.Reader) ([]Record, error) {
+	return readExportCSV(r, func(row map[string]string) (Record, bool, error) {
+		code := row["shortcode"]
+		if code == "" {
+			code = path.Base(row["shorturl"])
+		}
+		clicks := row["visits"]
+		if clicks == "" {
+			clicks = row["visitscount"]
+		}
+		record, err := newImportRecord(code, row["longurl"], row["title"], clicks, row["createdat"])
+		return record, true, err
+	})
+}
+
+// errPolrSecret fails the Polr links with a secret key, which
+// follows the code in their path and has no match in urlss
+var errPolrSecret = errors.New("Polr links with a secret key are not supported")
+
+// readPolr reads the CSV export of Polr. Disabled links are
+// left out and links with a secret key fail to import.
+func readPolr(r io.Reader) ([]Record, error) {
+	return readExportCSV(r, func(row map[string]string) (Record, bool, error) {
+		if disabled, _ := strconv.ParseBool(row["is_disabled"]); disabled {
+			return Record{}, false, nil
+		}
+		record, err := newImportRecord(row["short_url"], row["long_url"], "", row["clicks"], row["created_at"])
+		if row["secret_key"] != "" {
+			record.err = errPolrSecret
+		}
+		return record, true, err
+	})
+}
+
+// readExportCSV reads a CSV file with a header, giving convert
+// each row by the lower case names of its columns. Rows that
+// convert does not keep are left out.
+func readExportCSV(r io.Reader, convert func(map[string]string) (Record, bool, error)) (records []Record, err error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, errors.New("The export needs a header")
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+	for line := 2; ; line++ {
+		values, err := cr.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := map[string]string{}
+		for i, value := range values {
+			row[header[i]] = strings.TrimSpace(value)
+		}
+		record, keep, err := convert(row)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if keep {
+			records = append(records, record)
+		}
+	}
+}
+
+// newImportRecord makes a record of the fields of an export,
+// where clicks and created may be empty
+func newImportRecord(code, url, title, clicks, created string) (record Record, err error) {
+	record = Record{Code: code, Link: Link{URL: url, Title: title}}
+	if clicks != "" {
+		if record.Clicks, err = strconv.ParseInt(clicks, 10, 64); err != nil {
+			return record, errors.New("Invalid click count " + clicks)
+		}
+	}
+	if created != "" {
+		t, err := parseTimestamp(created)
+		if err != nil {
+			return record, err
+		}
+		record.Created = &t
+	}
+	return
+}
+
+// parseTimestamp reads the dates of exports, which are
+// in UTC unless they have an offset
+func parseTimestamp(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, errors.New("Invalid date " + s)
+}
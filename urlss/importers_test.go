@@ -0,0 +1,95 @@
+package urlss
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadYOURLS(t *testing.T) {
+	dump := "-- MySQL dump\n" +
+		"INSERT INTO `yourls_options` VALUES (1,'version','1.7');\n" +
+		"INSERT INTO `yourls_url` VALUES ('abc','https://example.com/a','It''s \\'a\\'','2019-03-04 05:06:07','127.0.0.1',12),\n" +
+		"('x1','https://example.com/b?q=(1,2);',NULL,'2019-03-05 00:00:00','::1',0);\n" +
+		"INSERT INTO `pre_url` (`keyword`, `url`, `clicks`) VALUES ('c','https://example.com/c',3);\n"
+	records, err := ReadRecords(strings.NewReader(dump), "yourls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Got %+v", records)
+	}
+	if r := records[0]; r.Code != "abc" || r.URL != "https://example.com/a" || r.Title != "It's 'a'" || r.Clicks != 12 || !r.Created.Equal(time.Date(2019, 3, 4, 5, 6, 7, 0, time.UTC)) {
+		t.Errorf("Got %+v", r)
+	}
+	if r := records[1]; r.URL != "https://example.com/b?q=(1,2);" || r.Title != "" {
+		t.Errorf("Got %+v", r)
+	}
+	if r := records[2]; r.Code != "c" || r.Clicks != 3 || r.Created != nil {
+		t.Errorf("Got %+v", r)
+	}
+	for _, bad := range []string{"CREATE TABLE x;", "INSERT INTO `yourls_url` VALUES ('a','b"} {
+		if _, err = ReadRecords(strings.NewReader(bad), "yourls"); err == nil {
+			t.Errorf("%q should fail", bad)
+		}
+	}
+}
+
+func TestReadShlinkPolr(t *testing.T) {
+	records, err := ReadRecords(strings.NewReader(`createdAt,domain,shortCode,shortUrl,longUrl,title,tags,visits
+2021-05-01T10:00:00+02:00,,docs,https://s.example.com/docs,https://docs.example.com/,Docs,,7
+2021-05-02T10:00:00+00:00,,,https://s.example.com/xyz,https://example.com/,,,0
+`), "shlink")
+	if err != nil || len(records) != 2 {
+		t.Fatalf("Got %+v, %v", records, err)
+	}
+	if r := records[0]; r.Code != "docs" || r.Title != "Docs" || r.Clicks != 7 || r.Created.Hour() != 8 {
+		t.Errorf("Got %+v", r)
+	}
+	if records[1].Code != "xyz" {
+		t.Errorf("Got %+v", records[1])
+	}
+
+	records, err = ReadRecords(strings.NewReader(`id,short_url,long_url,ip,creator,clicks,secret_key,is_disabled,is_custom,is_api,created_at,updated_at
+1,abc,https://example.com/a,127.0.0.1,admin,5,,0,0,0,2017-01-02 03:04:05,2017-01-02 03:04:05
+2,off,https://example.com/b,127.0.0.1,admin,1,,1,0,0,2017-01-02 03:04:05,2017-01-02 03:04:05
+3,key,https://example.com/c,127.0.0.1,admin,0,s3cret,0,1,0,2017-01-02 03:04:05,2017-01-02 03:04:05
+`), "polr")
+	if err != nil || len(records) != 2 {
+		t.Fatalf("Got %+v, %v", records, err)
+	}
+	if r := records[0]; r.Code != "abc" || r.Clicks != 5 || r.Created.Year() != 2017 {
+		t.Errorf("Got %+v", r)
+	}
+	report, err := newTestServer(t, nil).Import(records, ImportOptions{})
+	if err != nil || report.Created != 1 || len(report.Failed) != 1 || report.Failed[0].Code != "key" {
+		t.Errorf("Links with a secret key should fail, got %+v, %v", report, err)
+	}
+	if _, err = ReadRecords(strings.NewReader("short_url,long_url,clicks\nabc,https://example.com,many\n"), "polr"); err == nil {
+		t.Error("Invalid clicks should fail")
+	}
+}
+
+func TestImportKeepsStats(t *testing.T) {
+	s := newTestServer(t, nil)
+	records, _ := ReadRecords(strings.NewReader("INSERT INTO `yourls_url` VALUES ('old','https://example.com/old','Old','2015-01-01 00:00:00','',42);"), "yourls")
+	if report, err := s.Import(records, ImportOptions{}); err != nil || report.Created != 1 {
+		t.Fatalf("Got %+v, %v", report, err)
+	}
+	if stats := s.Stats("old"); stats.Clicks != 42 || stats.Created.Year() != 2015 {
+		t.Errorf("Got %+v", stats)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/old+", nil))
+	if body := w.Body.String(); !strings.Contains(body, "42 clicks") || strings.Contains(body, "0001-01-01") {
+		t.Errorf("Imported clicks have no last click, got %s", body)
+	}
+	if code, _ := s.Shorten("https://example.com/old"); code == "old" {
+		t.Error("Links with a title are not plain")
+	}
+	code, _ := s.Shorten("https://example.com/new")
+	if stats := s.Stats(code); stats.Created.IsZero() {
+		t.Error("New links should record when they were made")
+	}
+}
@@ -0,0 +1,55 @@
+package urlss
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// The legacy path-style grammar is everything after the first slash
+// of the request, so that typing http://urlss/example.com/page?x=1
+// shortens example.com/page?x=1. The edge cases are
+//
+//   /example.com/page?x=1            the query belongs to the URL
+//   /https:/example.com              slashes merged by a proxy are restored
+//   /https%3A%2F%2Fexample.com%2F    a fully encoded URL is decoded once
+//   /example.com/a%20b               other escapes are kept as they are
+//   /abcXYZ                          a bare code is looked up, never shortened
+//
+// Fragments never reach the server, so URLs with a "#" have to be
+// sent encoded via ?url= or the form.
+
+// codeRegexp matches strings that are looked up as short codes
+var codeRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// mergedSchemeRegexp matches a scheme whose "//" may have been
+// collapsed to "/"
+var mergedSchemeRegexp = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9+.-]*):/([^/])`)
+
+// encodedRegexp matches escaped characters that only appear when a
+// whole URL was percent-encoded into the path
+var encodedRegexp = regexp.MustCompile(`(?i)%(3A|2F|3F)`)
+
+// pathTarget returns what was typed after the host of a path-style request
+func (p urlPolicy) pathTarget(r *http.Request) string {
+	target := strings.TrimPrefix(r.URL.EscapedPath(), "/")
+	if encodedRegexp.MatchString(target) {
+		if unescaped, err := url.PathUnescape(target); err == nil {
+			target = unescaped
+		}
+	}
+	if m := mergedSchemeRegexp.FindStringSubmatch(target); m != nil && p.hasAuthority(strings.ToLower(m[1])) {
+		target = m[1] + "://" + target[len(m[1])+2:]
+	}
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	return target
+}
+
+// isCode reports whether a path-style target should be looked up
+// rather than shortened
+func isCode(target string) bool {
+	return codeRegexp.MatchString(target)
+}
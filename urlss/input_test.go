@@ -0,0 +1,68 @@
+package urlss
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestPathTarget(t *testing.T) {
+	s := newTestServer(t, nil)
+	for requestURI, target := range map[string]string{
+		"/":                                      "",
+		"/example.com":                           "example.com",
+		"/example.com/page?x=1&y=2":              "example.com/page?x=1&y=2",
+		"/https:/example.com/a":                  "https://example.com/a",
+		"/https://example.com/a":                 "https://example.com/a",
+		"/example.com/http-guide":                "example.com/http-guide",
+		"/example.com/a%20b":                     "example.com/a%20b",
+		"/https%3A%2F%2Fexample.com%2Fa%3Fb%3D1": "https://example.com/a?b=1",
+		"/example.com/search?q=a%26b":            "example.com/search?q=a%26b",
+		"/abcXYZ":                                "abcXYZ",
+	} {
+		r := httptest.NewRequest("GET", requestURI, nil)
+		if got := s.policy.pathTarget(r); got != target {
+			t.Errorf("%s: expected %s, got %s", requestURI, target, got)
+		}
+	}
+	if !isCode("abcXYZ") || isCode("example.com") || isCode("") {
+		t.Error("isCode is weird")
+	}
+}
+
+func TestCreateEndpoints(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/?url="+url.QueryEscape("https://example.org/a?b=1#frag"), nil))
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "Invalid URL") {
+		t.Errorf("?url= failed with %d", w.Code)
+	}
+	var fromQuery string
+	s.store.Get("https://example.org/a?b=1#frag", &fromQuery)
+	if fromQuery == "" {
+		t.Error("?url= did not shorten")
+	}
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/", strings.NewReader("url="+url.QueryEscape("https://example.org/a?b=1#frag")))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	s.ServeHTTP(w, req)
+	if !strings.Contains(w.Body.String(), fromQuery) {
+		t.Error("form POST should give the same code as ?url=")
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/"+fromQuery, nil))
+	if w.Code != 301 || w.Header().Get("Location") != "https://example.org/a?b=1#frag" {
+		t.Errorf("Got %d to %s", w.Code, w.Header().Get("Location"))
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/?url=not%20a%20url", nil))
+	if !strings.Contains(w.Body.String(), "Invalid URL") {
+		t.Error("Should report invalid URL")
+	}
+}
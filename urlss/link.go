@@ -0,0 +1,336 @@
+package urlss
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Link is the record stored under each short code
+type Link struct {
+	URL string `json:"url"`
+	// Title is a label for people, it does not change redirects
+	Title string `json:"title,omitempty"`
+	// Prefix merges the path and query appended to the
+	// short code into URL, so /code/a/b?x=1 redirects to URL/a/b?x=1
+	Prefix bool `json:"prefix,omitempty"`
+	// QueryConflict decides which value wins when a prefix link and
+	// the request share a query parameter: "request" (the default),
+	// "link", or "both" to keep them all
+	QueryConflict string `json:"query_conflict,omitempty"`
+	// Params are campaign parameters (utm_source, utm_campaign, ...)
+	// added to the destination at redirect time, replacing any
+	// parameter of the same name
+	Params map[string]string `json:"params,omitempty"`
+	// Rules are tried in order and the first that matches the
+	// request replaces URL, which is the fallback
+	Rules []Rule `json:"rules,omitempty"`
+	// Schedule replaces URL from the start of each entry on, when
+	// no rule matched. Starts without an offset are in Timezone.
+	Schedule []ScheduleEntry `json:"schedule,omitempty"`
+	Timezone string          `json:"timezone,omitempty"`
+	// Variants split the remaining requests between weighted URLs,
+	// chosen per Rotation and kept per visitor if Sticky
+	Variants []Variant `json:"variants,omitempty"`
+	Rotation string    `json:"rotation,omitempty"`
+	Sticky   bool      `json:"sticky,omitempty"`
+	// PasswordHash is set for links that ask for a password
+	// before redirecting, see hashPassword
+	PasswordHash string `json:"password_hash,omitempty"`
+	// Signed links only redirect through the
+	// expiring tokens made by signLink
+	Signed bool `json:"signed,omitempty"`
+}
+
+var queryConflicts = map[string]bool{"": true, "request": true, "link": true, "both": true}
+
+// plain reports whether a link has no options, only plain links
+// are deduplicated by their URL
+func (l Link) plain() bool {
+	return reflect.DeepEqual(l, Link{URL: l.URL})
+}
+
+// temporary reports whether the redirects of a link can change
+// without the link changing, browsers must not cache them
+func (l Link) temporary() bool {
+	return len(l.Schedule) > 0 || len(l.Variants) > 0 || l.Signed || l.PasswordHash != ""
+}
+
+// Lookup loads the link for a short code, other keys
+// of the store are not found
+func (s *Server) Lookup(code string) (l Link, err error) {
+	if !isCode(code) {
+		err = notFoundError(code)
+		return
+	}
+	var raw json.RawMessage
+	if s.store.Get(code, &raw) != nil {
+		err = notFoundError(code)
+		return
+	}
+	if err = json.Unmarshal(raw, &l); err != nil {
+		// codes made before links had options only store the URL
+		err = json.Unmarshal(raw, &l.URL)
+	}
+	return
+}
+
+// AddLink stores a new link and returns its code, plain links
+// that were shortened before get their existing code
+func (s *Server) AddLink(l Link) (shortened string, err error) {
+	if l.URL == "" && len(l.Variants) > 0 {
+		l.URL = l.Variants[0].URL
+	}
+	if strings.TrimSpace(l.URL) == "" {
+		err = invalidURLError(errors.New("No URL given"))
+		return
+	}
+	l.URL, err = s.policy.canonicalURL(strings.TrimSpace(l.URL))
+	if err != nil {
+		return
+	}
+	if err = l.validate(s.policy); err != nil {
+		return
+	}
+	// Check if it is already a URL
+	if l.plain() && s.store.Get(l.URL, &shortened) == nil {
+		return
+	}
+	err = s.update(func() error {
+		if l.plain() && s.store.Get(l.URL, &shortened) == nil {
+			return nil
+		}
+		// Get a new shortend URL
+		shortened = s.NewCode()
+		s.store.Set(shortened, l)
+		s.store.Set(statsKey(shortened), Stats{Created: time.Now().UTC()})
+		if l.plain() {
+			s.store.Set(l.URL, shortened)
+		}
+		s.metrics.inc(&s.metrics.shortens)
+		s.log.Debug("Shortened", "code", shortened, "url", loggedURL(l.URL))
+		return nil
+	})
+	if err != nil {
+		shortened = ""
+	}
+	return
+}
+
+// validate checks the options of a link with a canonical URL
+func (l *Link) validate(p urlPolicy) error {
+	if !queryConflicts[l.QueryConflict] {
+		return errors.New("Unknown query conflict rule " + l.QueryConflict)
+	}
+	if len(l.Params) == 0 {
+		l.Params = nil
+	}
+	if len(l.Rules) == 0 {
+		l.Rules = nil
+	}
+	for i := range l.Rules {
+		if err := l.Rules[i].validate(p); err != nil {
+			return err
+		}
+	}
+	if err := l.validateSchedule(p); err != nil {
+		return err
+	}
+	if err := l.validateVariants(p); err != nil {
+		return err
+	}
+	if l.Prefix || l.Params != nil {
+		for _, u := range l.urls() {
+			if !webSchemes[urlScheme(u)] {
+				return errors.New("Only web links can have a prefix or parameters")
+			}
+		}
+	}
+	for key := range l.Params {
+		if key == "" {
+			return errors.New("Parameters need a name")
+		}
+	}
+	return nil
+}
+
+// urls returns every destination a link can redirect to
+func (l Link) urls() []string {
+	urls := []string{l.URL}
+	for _, rule := range l.Rules {
+		urls = append(urls, rule.URL)
+	}
+	for _, e := range l.Schedule {
+		urls = append(urls, e.URL)
+	}
+	for _, v := range l.Variants {
+		urls = append(urls, v.URL)
+	}
+	return urls
+}
+
+// UpdateLink applies change to the link stored under code
+func (s *Server) UpdateLink(code string, change func(l *Link) error) (l Link, err error) {
+	err = s.update(func() (err error) {
+		l, err = s.Lookup(code)
+		if err != nil {
+			return
+		}
+		wasPlain, variants := l.plain(), append([]Variant(nil), l.Variants...)
+		if err = change(&l); err != nil {
+			return
+		}
+		if err = l.validate(s.policy); err != nil {
+			return
+		}
+		if !reflect.DeepEqual(variants, l.Variants) {
+			// clicks are counted by the position of variants,
+			// the ones in memory are saved with the change
+			stats := s.storedStats(code)
+			stats.add(s.clicks.take(code))
+			stats.rekeyVariants(variants, l.Variants)
+			s.store.Set(statsKey(code), stats)
+		}
+		if wasPlain && !l.plain() {
+			// the URL should no longer get this code when shortened
+			var indexed string
+			if s.store.Get(l.URL, &indexed) == nil && indexed == code {
+				s.store.Delete(l.URL)
+			}
+		}
+		s.store.Set(code, l)
+		return
+	})
+	return
+}
+
+// Codes returns the short codes in the store in order
+func (s *Server) Codes() []string {
+	var codes []string
+	for _, key := range s.store.Keys() {
+		if isCode(key) {
+			codes = append(codes, key)
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// deletedKey marks a deleted code, which is not handed out again
+// so that shared short links don't lead to someone else's URL
+func deletedKey(code string) string {
+	return "deleted/" + code
+}
+
+// DeleteLink removes the link of a code together with its stats,
+// the code stays taken
+func (s *Server) DeleteLink(code string) error {
+	if !isCode(code) {
+		return notFoundError(code)
+	}
+	return s.update(func() error {
+		l, err := s.Lookup(code)
+		if err != nil {
+			return notFoundError(code)
+		}
+		var indexed string
+		if s.store.Get(l.URL, &indexed) == nil && indexed == code {
+			s.store.Delete(l.URL)
+		}
+		s.store.Delete(code)
+		s.store.Delete(statsKey(code))
+		s.store.Set(deletedKey(code), time.Now().UTC())
+		s.clicks.take(code)
+		s.log.Debug("Deleted", "code", code)
+		return nil
+	})
+}
+
+// splitCode splits a path-style target into the short
+// code and the path and query appended to it
+func splitCode(target string) (code, extra string) {
+	if i := strings.IndexAny(target, "/?"); i >= 0 {
+		return target[:i], target[i:]
+	}
+	return target, ""
+}
+
+// target picks the URL a request for the link is sent to: the first
+// matching rule, else the active schedule entry, else a variant,
+// else URL. w and r may be nil.
+func (l Link) target(code, path string, w http.ResponseWriter, r *http.Request, rot *rotation) (string, int) {
+	if target, rule := l.route(r); rule >= 0 {
+		return target, -1
+	}
+	if active, _ := l.scheduled(now()); active >= 0 {
+		return l.Schedule[active].URL, -1
+	}
+	if variant := l.pickVariant(code, path, w, r, rot); variant >= 0 {
+		return l.Variants[variant].URL, variant
+	}
+	return l.URL, -1
+}
+
+// destination is where a request for the link's code plus
+// extra (as returned by splitCode) is redirected, given the
+// target picked for it
+func (l Link) destination(target, extra string) (string, error) {
+	if extra == "" && l.Params == nil {
+		return target, nil
+	}
+	if extra != "" && !l.Prefix {
+		return "", errors.New("not a prefix link")
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", err
+	}
+	extraURL, err := url.Parse(extra)
+	if err != nil {
+		return "", err
+	}
+	if extraURL.Path != "" && extraURL.Path != "/" {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + strings.TrimPrefix(extraURL.Path, "/")
+		u.RawPath = ""
+	}
+	u.RawQuery = mergeQuery(u.RawQuery, extraURL.RawQuery, l.QueryConflict)
+	if l.Params != nil {
+		params := url.Values{}
+		for key, value := range l.Params {
+			params.Set(key, value)
+		}
+		u.RawQuery = mergeQuery(u.RawQuery, params.Encode(), "request")
+	}
+	return u.String(), nil
+}
+
+// mergeQuery combines the query of a link with the query of
+// a request according to a QueryConflict rule
+func mergeQuery(linkQuery, requestQuery, conflict string) string {
+	if requestQuery == "" {
+		return linkQuery
+	}
+	if linkQuery == "" {
+		return requestQuery
+	}
+	linkValues, _ := url.ParseQuery(linkQuery)
+	requestValues, _ := url.ParseQuery(requestQuery)
+	for key, values := range requestValues {
+		if _, ok := linkValues[key]; ok {
+			switch conflict {
+			case "link":
+				continue
+			case "both":
+				linkValues[key] = append(linkValues[key], values...)
+				continue
+			}
+		}
+		linkValues[key] = values
+	}
+	return linkValues.Encode()
+}
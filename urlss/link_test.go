@@ -0,0 +1,208 @@
+package urlss
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDestination(t *testing.T) {
+	l := Link{URL: "https://docs.example.com/v2/?lang=en", Prefix: true}
+	for extra, destination := range map[string]string{
+		"":                "https://docs.example.com/v2/?lang=en",
+		"/":               "https://docs.example.com/v2/?lang=en",
+		"/api/index.html": "https://docs.example.com/v2/api/index.html?lang=en",
+		"/a?x=1":          "https://docs.example.com/v2/a?lang=en&x=1",
+		"?lang=de":        "https://docs.example.com/v2/?lang=de",
+	} {
+		got, err := l.destination(l.URL, extra)
+		if err != nil || got != destination {
+			t.Errorf("%s: expected %s, got %s (%v)", extra, destination, got, err)
+		}
+	}
+
+	for conflict, query := range map[string]string{
+		"request": "lang=de",
+		"link":    "lang=en",
+		"both":    "lang=en&lang=de",
+	} {
+		if got := mergeQuery("lang=en", "lang=de", conflict); got != query {
+			t.Errorf("%s: expected %s, got %s", conflict, query, got)
+		}
+	}
+
+	if _, err := (Link{URL: "https://example.com"}).destination("https://example.com", "/more"); err == nil {
+		t.Error("Plain links should not pass paths through")
+	}
+}
+
+func TestLegacyLink(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.store.Set("legacyCode", "http://example.net")
+	l, err := s.Lookup("legacyCode")
+	if err != nil || l.URL != "http://example.net" {
+		t.Errorf("Got %+v, %v", l, err)
+	}
+}
+
+func TestLookupOnlyCodes(t *testing.T) {
+	s := newTestServer(t, nil)
+	code, _ := s.Shorten("mailto:a@example.com")
+	for _, key := range []string{"mailto:a@example.com", statsKey(code)} {
+		if _, err := s.Lookup(key); errorStatus(err) != 404 {
+			t.Errorf("%s should not be found, got %v", key, err)
+		}
+		if err := s.DeleteLink(key); errorStatus(err) != 404 {
+			t.Errorf("%s should not be deleted, got %v", key, err)
+		}
+	}
+	for _, method := range []string{"GET", "PATCH"} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, apiRequest(method, "/api/links/mailto:a@example.com", strings.NewReader(`{"title":"x"}`)))
+		if w.Code != 404 {
+			t.Errorf("%s: got %d: %s", method, w.Code, w.Body.String())
+		}
+	}
+	if again, _ := s.Shorten("mailto:a@example.com"); again != code {
+		t.Errorf("The URL should keep its code %s, got %s", code, again)
+	}
+}
+
+func TestAPICreate(t *testing.T) {
+	s := newTestServer(t, nil)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/api/links", strings.NewReader(`{"url":"https://docs.example.com/","prefix":true}`)))
+	if w.Code != 201 {
+		t.Fatalf("Got %d: %s", w.Code, w.Body.String())
+	}
+	var created apiLink
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if !created.Prefix || created.Code == "" {
+		t.Errorf("Got %+v", created)
+	}
+	if plain, _ := s.Shorten("https://docs.example.com/"); plain == created.Code {
+		t.Error("Prefix links should not share a code with plain links")
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/"+created.Code+"/guide/intro?x=1", nil))
+	if w.Header().Get("Location") != "https://docs.example.com/guide/intro?x=1" {
+		t.Errorf("Got %d to %s", w.Code, w.Header().Get("Location"))
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/api/links", strings.NewReader(`{"url":"mailto:a@example.com","prefix":true}`)))
+	if w.Code != 400 {
+		t.Errorf("Got %d for a mailto prefix link", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, apiRequest("GET", "/api/links/"+created.Code, nil))
+	if w.Code != 200 || !strings.Contains(w.Body.String(), `"prefix":true`) {
+		t.Errorf("Got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCampaignParams(t *testing.T) {
+	s := newTestServer(t, nil)
+	code, err := s.AddLink(Link{URL: "https://shop.example.com/?utm_source=old&id=3", Params: map[string]string{"utm_source": "newsletter"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/"+code, nil))
+	if w.Header().Get("Location") != "https://shop.example.com/?id=3&utm_source=newsletter" {
+		t.Errorf("Got %s", w.Header().Get("Location"))
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, apiRequest("PATCH", "/api/links/"+code, strings.NewReader(`{"params":{"utm_source":"ads","utm_medium":"cpc"}}`)))
+	if w.Code != 200 {
+		t.Fatalf("Got %d: %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/"+code, nil))
+	if w.Header().Get("Location") != "https://shop.example.com/?id=3&utm_medium=cpc&utm_source=ads" {
+		t.Errorf("Got %s", w.Header().Get("Location"))
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/api/links/"+code+"/stats", nil))
+	var stats struct {
+		Stats  Stats
+		Params map[string]string
+	}
+	json.Unmarshal(w.Body.Bytes(), &stats)
+	if stats.Stats.Clicks != 2 || stats.Params["utm_medium"] != "cpc" {
+		t.Errorf("Got %s", w.Body.String())
+	}
+
+	// a plain link given parameters is no longer the code for its URL
+	plain, _ := s.Shorten("https://plain.example.com")
+	s.UpdateLink(plain, func(l *Link) error {
+		l.Params = map[string]string{"ref": "x"}
+		return nil
+	})
+	if again, _ := s.Shorten("https://plain.example.com"); again == plain {
+		t.Error("Should get a new code once the old one has parameters")
+	}
+}
+
+func TestAPIListAndDelete(t *testing.T) {
+	s := newTestServer(t, nil)
+	var codes []string
+	for _, u := range []string{"https://a.example.com", "https://b.example.com", "https://c.example.com"} {
+		code, err := s.Shorten(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		codes = append(codes, code)
+	}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, apiRequest("GET", "/api/links?offset=1&limit=1", nil))
+	var page struct {
+		Links []apiLink
+		Total int
+	}
+	json.Unmarshal(w.Body.Bytes(), &page)
+	if w.Code != 200 || page.Total != 3 || len(page.Links) != 1 || page.Links[0].Code != s.Codes()[1] {
+		t.Errorf("Got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, apiRequest("DELETE", "/api/links/"+codes[0], nil))
+	if w.Code != 204 {
+		t.Errorf("Got %d: %s", w.Code, w.Body.String())
+	}
+	if _, err := s.Lookup(codes[0]); err == nil {
+		t.Error("The link should be deleted")
+	}
+	var indexed string
+	if u, _ := s.Normalize("https://a.example.com"); s.store.Get(u, &indexed) == nil {
+		t.Error("The URL should no longer point to the deleted code")
+	}
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, apiRequest("DELETE", "/api/links/"+codes[0]+"x", nil))
+	if w.Code != 404 {
+		t.Errorf("Got %d for an unknown code", w.Code)
+	}
+}
+
+func TestDeletedCodesStayTaken(t *testing.T) {
+	s := newTestServer(t, func(cfg *Config) {
+		// one letter codes run out after a and b
+		cfg.Codes = CodesConfig{Alphabet: "ab", MinLength: 1, MaxLength: 2}
+	})
+	deleted, _ := s.Shorten("https://example.com/deleted")
+	if err := s.DeleteLink(deleted); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if code, err := s.Shorten(fmt.Sprintf("https://example.com/%d", i)); err != nil || code == deleted {
+			t.Errorf("Got %s, %v after deleting %s", code, err, deleted)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+// +build !windows
+
+package urlss
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes the lock of the data file at path, which is held
+// on path.lock by every process that changes it
+func lockFile(path string) (unlock func(), err error) {
+	f, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
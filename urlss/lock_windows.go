@@ -0,0 +1,30 @@
+package urlss
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// lockTimeout is how long lockFile waits for another process
+const lockTimeout = 10 * time.Second
+
+// lockFile takes the lock of the data file at path by creating
+// path.lock, which is removed again by unlock
+func lockFile(path string) (unlock func(), err error) {
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path + ".lock") }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.New("timed out waiting for " + path + ".lock")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
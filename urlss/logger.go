@@ -0,0 +1,248 @@
+package urlss
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LogConfig shapes the log of the server
+type LogConfig struct {
+	// Format is logfmt or json
+	Format string `yaml:"format"`
+	// Level is the least severe level logged,
+	// debug, info, warn or error
+	Level string `yaml:"level"`
+	// RedactURLs hides destinations, and the paths and
+	// queries of requests that may contain them
+	RedactURLs bool `yaml:"redact_urls"`
+	// RedactIPs keeps only the network of client addresses,
+	// /24 for IPv4 and /48 for IPv6
+	RedactIPs bool `yaml:"redact_ips"`
+}
+
+// logLevels are the levels by their names
+var logLevels = map[string]int{"debug": 0, "info": 1, "warn": 2, "error": 3}
+
+var logFormats = map[string]bool{"logfmt": true, "json": true}
+
+// Logger writes one line per event, as logfmt or JSON, with
+// the fields given as key and value pairs
+type Logger struct {
+	mu     sync.Mutex
+	w      io.Writer
+	cfg    LogConfig
+	level  int
+	format string
+}
+
+// NewLogger returns a logger writing to w as cfg says,
+// unknown formats and levels are logfmt and info
+func NewLogger(w io.Writer, cfg LogConfig) *Logger {
+	l := &Logger{w: w, cfg: cfg, level: logLevels["info"], format: "logfmt"}
+	if level, ok := logLevels[cfg.Level]; ok {
+		l.level = level
+	}
+	if logFormats[cfg.Format] {
+		l.format = cfg.Format
+	}
+	return l
+}
+
+// Debug, Info, Warn and Error log msg with the fields at their level
+func (l *Logger) Debug(msg string, fields ...interface{}) { l.write("debug", msg, fields) }
+func (l *Logger) Info(msg string, fields ...interface{})  { l.write("info", msg, fields) }
+func (l *Logger) Warn(msg string, fields ...interface{})  { l.write("warn", msg, fields) }
+func (l *Logger) Error(msg string, fields ...interface{}) { l.write("error", msg, fields) }
+
+// loggedURL and clientIP are field values that are redacted
+// as the LogConfig of the logger says
+type loggedURL string
+type clientIP string
+
+// redacted replaces the values that are not logged
+const redacted = "[redacted]"
+
+func (l *Logger) value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case loggedURL:
+		if l.cfg.RedactURLs {
+			return redacted
+		}
+		return string(v)
+	case clientIP:
+		if l.cfg.RedactIPs {
+			return maskIP(string(v))
+		}
+		return string(v)
+	case error:
+		return v.Error()
+	case time.Duration:
+		return v.Seconds()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return v
+}
+
+// maskIP keeps the network of an address, /24 for
+// IPv4 and /48 for IPv6
+func maskIP(s string) string {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return redacted
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(48, 128)).String()
+}
+
+func (l *Logger) write(level, msg string, fields []interface{}) {
+	if l == nil || logLevels[level] < l.level {
+		return
+	}
+	pairs := append([]interface{}{"time", time.Now().UTC().Format("2006-01-02T15:04:05.000Z07:00"), "level", level, "msg", msg}, fields...)
+	if len(pairs)%2 == 1 {
+		pairs = append(pairs, nil)
+	}
+	var line bytes.Buffer
+	if l.format == "json" {
+		line.WriteByte('{')
+	}
+	for i := 0; i < len(pairs); i += 2 {
+		key, value := fmt.Sprint(pairs[i]), l.value(pairs[i+1])
+		if l.format == "json" {
+			if i > 0 {
+				line.WriteByte(',')
+			}
+			k, _ := json.Marshal(key)
+			v, err := json.Marshal(value)
+			if err != nil {
+				v, _ = json.Marshal(fmt.Sprint(value))
+			}
+			line.Write(k)
+			line.WriteByte(':')
+			line.Write(v)
+			continue
+		}
+		if i > 0 {
+			line.WriteByte(' ')
+		}
+		line.WriteString(key)
+		line.WriteByte('=')
+		line.WriteString(logfmtValue(value))
+	}
+	if l.format == "json" {
+		line.WriteByte('}')
+	}
+	line.WriteByte('\n')
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write(line.Bytes())
+}
+
+// logfmtValue quotes values that are empty or have
+// spaces, quotes or equal signs
+func logfmtValue(v interface{}) string {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return `""`
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		s = fmt.Sprint(v)
+	}
+	if s == "" || strings.ContainsAny(s, " =\"\\\t\r\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// RequestIDHeader carries the ID of a request, which is taken
+// from the request if a proxy set it and made otherwise
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey and logFieldsKey hold the ID of a request and
+// the fields its handlers add to its log line
+const (
+	requestIDKey = "urlss_request_id"
+	logFieldsKey = "urlss_log_fields"
+)
+
+var requestIDRegexp = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// logRequests logs one line per request, with its ID, and
+// answers 500 to requests whose handler panicked
+func (s *Server) logRequests(c *gin.Context) {
+	start := time.Now()
+	id := c.Request.Header.Get(RequestIDHeader)
+	if !requestIDRegexp.MatchString(id) {
+		id = hex.EncodeToString(randomBytes(8))
+	}
+	c.Set(requestIDKey, id)
+	c.Header(RequestIDHeader, id)
+	defer func() {
+		if err := recover(); err != nil {
+			s.log.Error("Panic", "request_id", id, "error", fmt.Sprint(err), "stack", string(debug.Stack()))
+			c.AbortWithStatus(http.StatusInternalServerError)
+		}
+		status := c.Writer.Status()
+		fields := []interface{}{
+			"request_id", id,
+			"method", c.Request.Method,
+			"path", s.logPath(c),
+			"status", status,
+			"duration", time.Since(start),
+			"bytes", c.Writer.Size(),
+			"client_ip", clientIP(c.ClientIP()),
+		}
+		if extra, ok := c.Get(logFieldsKey); ok {
+			fields = append(fields, extra.([]interface{})...)
+		}
+		switch {
+		case status >= 500:
+			s.log.Error("Request", fields...)
+		case probes[c.Request.URL.Path]:
+			s.log.Debug("Request", fields...)
+		default:
+			s.log.Info("Request", fields...)
+		}
+	}()
+	c.Next()
+}
+
+// addLogFields adds key and value pairs to the log line of a request
+func addLogFields(c *gin.Context, fields ...interface{}) {
+	if extra, ok := c.Get(logFieldsKey); ok {
+		fields = append(extra.([]interface{}), fields...)
+	}
+	c.Set(logFieldsKey, fields)
+}
+
+// logPath is the path of a request as it is logged. Unless URLs
+// are redacted it has the query, otherwise paths that are not
+// routes or short codes are redacted too.
+func (s *Server) logPath(c *gin.Context) interface{} {
+	if !s.log.cfg.RedactURLs {
+		return c.Request.URL.RequestURI()
+	}
+	path := c.Request.URL.Path
+	if _, ok := s.route(c); ok || isCode(path[1:]) || suffixRegexp.MatchString(path[1:]) {
+		return path
+	}
+	return "/" + redacted
+}
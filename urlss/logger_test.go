@@ -0,0 +1,76 @@
+package urlss
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(&buf, LogConfig{Format: "logfmt", Level: "info"})
+	l.Debug("Hidden")
+	l.Info("Saved", "file", "my links.json", "took", 1500*time.Millisecond, "error", errors.New(`bad "x"`), "empty", "")
+	line := buf.String()
+	if strings.Contains(line, "Hidden") || !strings.Contains(line, ` level=info msg=Saved file="my links.json" took=1.5 error="bad \"x\"" empty=""`+"\n") {
+		t.Errorf("Got %s", line)
+	}
+
+	buf.Reset()
+	l = NewLogger(&buf, LogConfig{Format: "json", Level: "debug", RedactURLs: true, RedactIPs: true})
+	l.Debug("Request", "url", loggedURL("https://example.com/secret"), "v4", clientIP("192.0.2.77"), "v6", clientIP("2001:db8:1:2::9"), "odd")
+	var fields map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &fields); err != nil {
+		t.Fatalf("%v: %s", err, buf.String())
+	}
+	if fields["level"] != "debug" || fields["url"] != redacted || fields["v4"] != "192.0.2.0" || fields["v6"] != "2001:db8:1::" || fields["odd"] != nil {
+		t.Errorf("Got %v", fields)
+	}
+}
+
+func TestRequestLog(t *testing.T) {
+	var buf bytes.Buffer
+	s := newTestServer(t, nil)
+	s.log = NewLogger(&buf, LogConfig{Format: "json", Level: "info", RedactURLs: true, RedactIPs: true})
+	request := func(path, id string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		buf.Reset()
+		r := httptest.NewRequest("GET", path, nil)
+		if id != "" {
+			r.Header.Set(RequestIDHeader, id)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 1 {
+			t.Fatalf("Expected one line for %s, got %s", path, buf.String())
+		}
+		var fields map[string]interface{}
+		json.Unmarshal([]byte(lines[0]), &fields)
+		return w, fields
+	}
+
+	w, fields := request("/https://example.com/secret", "abc-123")
+	if w.Header().Get(RequestIDHeader) != "abc-123" || fields["request_id"] != "abc-123" || strings.Contains(buf.String(), "secret") || fields["code"] == nil || fields["client_ip"] != "192.0.2.0" {
+		t.Errorf("Got %v", fields)
+	}
+	code := fields["code"].(string)
+	w, fields = request("/"+code, "bad id")
+	if id := w.Header().Get(RequestIDHeader); id == "" || id == "bad id" || fields["request_id"] != id {
+		t.Errorf("Got %q and %v", id, fields)
+	}
+	if fields["path"] != "/"+code || fields["status"] != float64(w.Code) || fields["destination"] != redacted {
+		t.Errorf("Got %v", fields)
+	}
+	if _, fields = request("/?url=https://example.com/secret", ""); fields["path"] != "/" {
+		t.Errorf("Got %v", fields)
+	}
+
+	s.log = NewLogger(&buf, LogConfig{Format: "logfmt", Level: "info"})
+	if _, fields = request("/healthz", ""); buf.Len() != 0 {
+		t.Errorf("Probes should only be logged at debug level, got %s", buf.String())
+	}
+}
@@ -0,0 +1,213 @@
+package urlss
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// latencyBuckets are the upper bounds of the latency
+// histograms in seconds, those of the Prometheus clients
+var latencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// histogram counts observations in latencyBuckets
+type histogram struct {
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+func (h *histogram) observe(d time.Duration) {
+	if h.buckets == nil {
+		h.buckets = make([]uint64, len(latencyBuckets))
+	}
+	seconds := d.Seconds()
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			h.buckets[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// write writes the series of h in the Prometheus text format,
+// labels are the labels of each series without braces
+func (h *histogram) write(w io.Writer, name, labels string) {
+	sep, series := "", ""
+	if labels != "" {
+		sep, series = ",", "{"+labels+"}"
+	}
+	for i, bound := range latencyBuckets {
+		var n uint64
+		if h.buckets != nil {
+			n = h.buckets[i]
+		}
+		fmt.Fprintf(w, "%s_bucket{%s%sle=\"%s\"} %d\n", name, labels, sep, strconv.FormatFloat(bound, 'g', -1, 64), n)
+	}
+	fmt.Fprintf(w, "%s_bucket{%s%sle=\"+Inf\"} %d\n", name, labels, sep, h.count)
+	fmt.Fprintf(w, "%s_sum%s %g\n", name, series, h.sum)
+	fmt.Fprintf(w, "%s_count%s %d\n", name, series, h.count)
+}
+
+// route is the method and registered path of requests
+type route struct {
+	method, path string
+}
+
+// metrics are what the server counts for /metrics
+type metrics struct {
+	mu           sync.Mutex
+	shortens     uint64
+	redirects    uint64
+	misses       uint64
+	saveFailures uint64
+	saves        histogram
+	requests     map[route]*histogram
+}
+
+// inc adds one to a counter of m
+func (m *metrics) inc(counter *uint64) {
+	m.mu.Lock()
+	*counter++
+	m.mu.Unlock()
+}
+
+// observeSave records how long saving the data file took
+func (m *metrics) observeSave(d time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.saves.observe(d)
+	if err != nil {
+		m.saveFailures++
+	}
+}
+
+// observeRequest records how long a request to r took
+func (m *metrics) observeRequest(r route, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.requests == nil {
+		m.requests = make(map[route]*histogram)
+	}
+	h, ok := m.requests[r]
+	if !ok {
+		h = new(histogram)
+		m.requests[r] = h
+	}
+	h.observe(d)
+}
+
+// instrument times the requests to each route, other
+// requests are counted under "*"
+func (s *Server) instrument(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+	path, ok := s.route(c)
+	if !ok {
+		path = "*"
+	}
+	s.metrics.observeRequest(route{c.Request.Method, path}, time.Since(start))
+}
+
+// route is the path the handler of a request is registered at
+func (s *Server) route(c *gin.Context) (string, bool) {
+	path, ok := s.routes[c.Request.Method+" "+c.HandlerName()]
+	return path, ok
+}
+
+// handleMetrics writes the metrics in the Prometheus text format
+func (s *Server) handleMetrics(c *gin.Context) {
+	ready, links := 0, 0
+	if s.isReady() {
+		ready, links = 1, len(s.Codes())
+	}
+	s.fileMu.Lock()
+	size := s.file.size
+	s.fileMu.Unlock()
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", s.metrics.text(ready, links, size))
+}
+
+// text is the Prometheus text format of m and the gauges
+func (m *metrics) text(ready, links int, size int64) []byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var w bytes.Buffer
+	gauge := func(name, help string, value interface{}) {
+		fmt.Fprintf(&w, "# HELP %s %s\n# TYPE %s gauge\n%s %v\n", name, help, name, name, value)
+	}
+	counter := func(name, help string, value uint64) {
+		fmt.Fprintf(&w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, value)
+	}
+	gauge("urlss_ready", "Whether the links are loaded and the server is not shutting down.", ready)
+	gauge("urlss_links", "Links in the store.", links)
+	counter("urlss_shortens_total", "Links made.", m.shortens)
+	counter("urlss_redirects_total", "Requests redirected to a link.", m.redirects)
+	counter("urlss_lookup_misses_total", "Requests for codes that could not be found.", m.misses)
+	counter("urlss_store_save_failures_total", "Saves of the data file that failed.", m.saveFailures)
+	gauge("urlss_store_size_bytes", "Size of the data file.", size)
+	w.WriteString("# HELP urlss_store_save_duration_seconds Time taken to save the data file.\n# TYPE urlss_store_save_duration_seconds histogram\n")
+	m.saves.write(&w, "urlss_store_save_duration_seconds", "")
+
+	w.WriteString("# HELP urlss_http_request_duration_seconds Time taken to answer requests, by route.\n# TYPE urlss_http_request_duration_seconds histogram\n")
+	routes := make([]route, 0, len(m.requests))
+	for r := range m.requests {
+		routes = append(routes, r)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].path != routes[j].path {
+			return routes[i].path < routes[j].path
+		}
+		return routes[i].method < routes[j].method
+	})
+	for _, r := range routes {
+		m.requests[r].write(&w, "urlss_http_request_duration_seconds", fmt.Sprintf("method=%q,route=%q", r.method, r.path))
+	}
+	return w.Bytes()
+}
+
+// isReady reports whether the links are loaded and
+// the server is not shutting down
+func (s *Server) isReady() bool {
+	return atomic.LoadInt32(&s.ready) == 1
+}
+
+// handleHealth answers as long as the process runs
+func (s *Server) handleHealth(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// handleReady answers 503 until the links are loaded
+// and once the server is shutting down
+func (s *Server) handleReady(c *gin.Context) {
+	if s.isReady() {
+		c.JSON(http.StatusOK, gin.H{"status": "ready"})
+		return
+	}
+	s.fileMu.Lock()
+	loadErr, closed := s.loadErr, s.closed
+	s.fileMu.Unlock()
+	switch {
+	case closed:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "shutting down"})
+	case loadErr != nil:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "failed", "error": loadErr.Error()})
+	default:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "loading"})
+	}
+}
+
+// probes are the paths that answer before the server is ready
+var probes = map[string]bool{"/healthz": true, "/readyz": true, "/metrics": true}
+
+// errNotReady answers requests until the links are loaded
+var errNotReady = &statusError{http.StatusServiceUnavailable, errors.New("The links are not available yet")}
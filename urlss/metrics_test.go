@@ -0,0 +1,82 @@
+package urlss
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProbes(t *testing.T) {
+	saved := newTestServer(t, nil)
+	code, _ := saved.Shorten("https://example.com/saved")
+	s, err := NewServer(saved.cfg, nil, NewLogger(ioutil.Discard, LogConfig{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+	for start := time.Now(); get("/readyz").Code != http.StatusOK; time.Sleep(time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatalf("The server should get ready, got %s", get("/readyz").Body.String())
+		}
+	}
+	if w := get("/" + code); w.Header().Get("Location") != "https://example.com/saved" {
+		t.Errorf("The loaded links should redirect, got %d", w.Code)
+	}
+	s.Close()
+	if w := get("/readyz"); w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "shutting down") {
+		t.Errorf("Got %d: %s after closing", w.Code, w.Body.String())
+	}
+
+	// a broken data file is not loaded, nor overwritten
+	ioutil.WriteFile(saved.cfg.Data, []byte("broken"), 0644)
+	broken, _ := NewServer(saved.cfg, nil, NewLogger(ioutil.Discard, LogConfig{}))
+	s = broken
+	for start := time.Now(); !strings.Contains(get("/readyz").Body.String(), "failed"); time.Sleep(time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatalf("Got %s", get("/readyz").Body.String())
+		}
+	}
+	if w := get("/healthz"); w.Code != http.StatusOK {
+		t.Errorf("Got %d for /healthz", w.Code)
+	}
+	if w := get("/?url=https://example.com"); w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Errorf("Got %d before the links are loaded", w.Code)
+	}
+	if data, _ := ioutil.ReadFile(saved.cfg.Data); string(data) != "broken" {
+		t.Error("The data file should be left alone")
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	s := newTestServer(t, nil)
+	code, _ := s.Shorten("https://example.com/counted")
+	for _, path := range []string{"/" + code, "/" + code, "/missing", "/api/links/" + code} {
+		s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("Got %d: %s", w.Code, w.Header())
+	}
+	for _, line := range []string{
+		"urlss_ready 1",
+		"urlss_links 1",
+		"urlss_shortens_total 1",
+		"urlss_redirects_total 2",
+		"urlss_lookup_misses_total 1",
+		"urlss_store_save_duration_seconds_count 1",
+		`urlss_http_request_duration_seconds_bucket{method="GET",route="*",le="+Inf"} 3`,
+		`urlss_http_request_duration_seconds_count{method="GET",route="/api/links/:code"} 1`,
+	} {
+		if !strings.Contains(w.Body.String(), "\n"+line+"\n") {
+			t.Errorf("Missing %s in\n%s", line, w.Body.String())
+		}
+	}
+}
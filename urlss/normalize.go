@@ -0,0 +1,136 @@
+package urlss
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/purell"
+	"github.com/goware/urlx"
+	"golang.org/x/net/idna"
+)
+
+// defaultNormalizeFlags are the flags urlx.Normalize applies
+const defaultNormalizeFlags purell.NormalizationFlags = purell.FlagRemoveDefaultPort |
+	purell.FlagDecodeDWORDHost | purell.FlagDecodeOctalHost | purell.FlagDecodeHexHost |
+	purell.FlagRemoveUnnecessaryHostDots | purell.FlagRemoveDotSegments | purell.FlagRemoveDuplicateSlashes |
+	purell.FlagUppercaseEscapes | purell.FlagDecodeUnnecessaryEscapes | purell.FlagEncodeNecessaryEscapes |
+	purell.FlagSortQuery
+
+// normalizeFlagNames are the names accepted by the -normalize flag
+var normalizeFlagNames = map[string]purell.NormalizationFlags{
+	"default":                  defaultNormalizeFlags,
+	"safe":                     purell.FlagsSafe,
+	"usually-safe":             purell.FlagsUsuallySafeGreedy,
+	"unsafe":                   purell.FlagsUnsafeGreedy,
+	"remove-fragment":          purell.FlagRemoveFragment,
+	"remove-www":               purell.FlagRemoveWWW,
+	"add-www":                  purell.FlagAddWWW,
+	"remove-trailing-slash":    purell.FlagRemoveTrailingSlash,
+	"add-trailing-slash":       purell.FlagAddTrailingSlash,
+	"remove-directory-index":   purell.FlagRemoveDirectoryIndex,
+	"remove-dot-segments":      purell.FlagRemoveDotSegments,
+	"remove-duplicate-slashes": purell.FlagRemoveDuplicateSlashes,
+	"remove-default-port":      purell.FlagRemoveDefaultPort,
+	"force-http":               purell.FlagForceHTTP,
+	"sort-query":               purell.FlagSortQuery,
+}
+
+// NormalizePolicy decides when two submitted URLs are
+// the same destination and so share a short code
+type NormalizePolicy struct {
+	// Flags are the purell normalizations to apply
+	Flags purell.NormalizationFlags
+	// StripParams are query parameters to drop, either exact
+	// names or prefixes ending in "*" such as "utm_*"
+	StripParams []string
+}
+
+// parseNormalizePolicy builds a policy from a comma separated list
+// of flag names (a leading "-" removes a flag) and a comma separated
+// list of query parameters to strip
+func parseNormalizePolicy(flagList, stripList string) (p NormalizePolicy, err error) {
+	for _, name := range splitList(flagList) {
+		remove := strings.HasPrefix(name, "-")
+		f, ok := normalizeFlagNames[strings.TrimPrefix(name, "-")]
+		if !ok {
+			err = fmt.Errorf("unknown normalization %q", name)
+			return
+		}
+		if remove {
+			p.Flags &^= f
+		} else {
+			p.Flags |= f
+		}
+	}
+	p.StripParams = splitList(stripList)
+	return
+}
+
+// Normalize returns the canonical string for a URL parsed by urlx.Parse
+func (p NormalizePolicy) Normalize(u *url.URL) (string, error) {
+	host, port, err := urlx.SplitHostPort(u)
+	if err != nil {
+		return "", err
+	}
+	// Decode Punycode, as urlx.Normalize does
+	host, err = idna.ToUnicode(host)
+	if err != nil {
+		return "", err
+	}
+	u.Host = strings.ToLower(host)
+	if port != "" {
+		u.Host += ":" + port
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.RawQuery = p.stripQuery(u.RawQuery)
+	return purell.NormalizeURL(u, p.Flags), nil
+}
+
+// stripQuery removes the stripped parameters from a raw query
+// while keeping the order of the rest
+func (p NormalizePolicy) stripQuery(rawQuery string) string {
+	if rawQuery == "" || len(p.StripParams) == 0 {
+		return rawQuery
+	}
+	kept := []string{}
+	for _, pair := range strings.Split(rawQuery, "&") {
+		key := pair
+		if i := strings.Index(pair, "="); i >= 0 {
+			key = pair[:i]
+		}
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		if pair != "" && !p.strips(key) {
+			kept = append(kept, pair)
+		}
+	}
+	return strings.Join(kept, "&")
+}
+
+func (p NormalizePolicy) strips(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range p.StripParams {
+		s = strings.ToLower(s)
+		if strings.HasSuffix(s, "*") {
+			if strings.HasPrefix(key, strings.TrimSuffix(s, "*")) {
+				return true
+			}
+		} else if key == s {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList splits a comma separated flag value, dropping blanks
+func splitList(s string) []string {
+	list := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,40 @@
+package urlss
+
+import (
+	"testing"
+
+	"github.com/goware/urlx"
+)
+
+func TestNormalize(t *testing.T) {
+	for _, test := range []struct {
+		flags, strip string
+		in, out      string
+	}{
+		{"default", "utm_*,fbclid", "example.com/page?utm_source=a&b=2&a=1", "http://example.com/page?a=1&b=2"},
+		{"default", "utm_*,fbclid", "example.com/page?utm_source=b&fbclid=x", "http://example.com/page"},
+		{"default,-sort-query", "gclid", "example.com/?b=2&gclid=z&a=1", "http://example.com/?b=2&a=1"},
+		{"default,remove-fragment,remove-www", "", "www.example.com/a#top", "http://example.com/a"},
+		{"default", "", "www.example.com/a#top", "http://www.example.com/a#top"},
+	} {
+		p, err := parseNormalizePolicy(test.flags, test.strip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		u, err := urlx.Parse(test.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := p.Normalize(u)
+		if err != nil {
+			t.Error(err)
+		}
+		if got != test.out {
+			t.Errorf("%s: expected %s, got %s", test.in, test.out, got)
+		}
+	}
+
+	if _, err := parseNormalizePolicy("default,bogus", ""); err == nil {
+		t.Error("Should reject unknown normalization")
+	}
+}
@@ -0,0 +1,206 @@
+package urlss
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/schollz/urlss/signed"
+)
+
+const passwordIterations = 100000
+
+var (
+	errPasswordRequired = &statusError{http.StatusUnauthorized, errors.New("This link needs a password")}
+	errTooManyAttempts  = rateLimitedError(errors.New("Too many wrong passwords, try again later"))
+)
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// pbkdf2 derives a key from a password with HMAC-SHA256 (RFC 8018)
+func pbkdf2(password, salt []byte, iterations int) []byte {
+	prf := hmac.New(sha256.New, password)
+	prf.Write(salt)
+	prf.Write([]byte{0, 0, 0, 1})
+	u := prf.Sum(nil)
+	key := append([]byte{}, u...)
+	for i := 1; i < iterations; i++ {
+		prf.Reset()
+		prf.Write(u)
+		u = prf.Sum(u[:0])
+		for j := range key {
+			key[j] ^= u[j]
+		}
+	}
+	return key
+}
+
+// hashPassword returns the string stored for a password
+func hashPassword(password string) string {
+	salt := randomBytes(16)
+	return fmt.Sprintf("pbkdf2-sha256$%d$%s$%s", passwordIterations,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(pbkdf2([]byte(password), salt, passwordIterations)))
+}
+
+// checkPassword compares a password with a string from hashPassword
+func checkPassword(password, hash string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2-sha256" {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(key, pbkdf2([]byte(password), salt, iterations))
+}
+
+// accessCookie is the name of the cookie that unlocks a code
+func accessCookie(code string) string {
+	return "urlss_access_" + code
+}
+
+// hasAccess reports whether r carries an unexpired
+// access cookie for a protected code
+func (s *Server) hasAccess(r *http.Request, code string) bool {
+	if r == nil {
+		return false
+	}
+	c, err := r.Cookie(accessCookie(code))
+	if err != nil {
+		return false
+	}
+	cookieCode, _, err := signed.Verify(s.derivedKey("access cookie"), c.Value, time.Now())
+	return err == nil && cookieCode == code
+}
+
+// attempts counts the recent wrong passwords per address and code
+type attempts struct {
+	sync.Mutex
+	failed map[string][]time.Time
+}
+
+// throttled reports whether an address used up its attempts on a
+// code, and records a failure if failed is set
+func (s *Server) throttled(key string, failed bool) bool {
+	s.attempts.Lock()
+	defer s.attempts.Unlock()
+	recent := []time.Time{}
+	for _, t := range s.attempts.failed[key] {
+		if time.Since(t) < s.cfg.Security.AttemptWindow {
+			recent = append(recent, t)
+		}
+	}
+	if failed {
+		recent = append(recent, time.Now())
+	}
+	if len(recent) == 0 {
+		delete(s.attempts.failed, key)
+	} else {
+		s.attempts.failed[key] = recent
+	}
+	return len(recent) >= s.cfg.Security.PasswordAttempts
+}
+
+// attemptAddress is the address wrong passwords are counted for.
+// Behind a trusted proxy it is the last X-Forwarded-For entry, the
+// one the proxy added, since clients can send any before it.
+func (s *Server) attemptAddress(r *http.Request) string {
+	if s.cfg.TrustProxy {
+		forwarded := strings.Split(strings.Join(r.Header["X-Forwarded-For"], ","), ",")
+		if last := strings.TrimSpace(forwarded[len(forwarded)-1]); last != "" {
+			return last
+		}
+		if real := strings.TrimSpace(r.Header.Get("X-Real-Ip")); real != "" {
+			return real
+		}
+	}
+	host, _, err := net.SplitHostPort(strings.TrimSpace(r.RemoteAddr))
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// unlock checks the password POSTed for the code in target. On
+// success it grants access for the rest of the request and with a
+// cookie, otherwise it renders the prompt again and returns false.
+func (s *Server) unlock(c *gin.Context, target string) bool {
+	code, _, _, err := s.resolveCode(target)
+	if err != nil {
+		return true
+	}
+	l, err := s.Lookup(code)
+	if err != nil || l.PasswordHash == "" {
+		return true
+	}
+	key := s.attemptAddress(c.Request) + " " + code
+	if s.throttled(key, false) {
+		renderPassword(c, errorStatus(errTooManyAttempts), errTooManyAttempts.Error())
+		return false
+	}
+	if !checkPassword(c.PostForm("password"), l.PasswordHash) {
+		if s.throttled(key, true) {
+			renderPassword(c, errorStatus(errTooManyAttempts), errTooManyAttempts.Error())
+		} else {
+			renderPassword(c, http.StatusUnauthorized, "Wrong password")
+		}
+		return false
+	}
+	// signed links are visited at their tokens, the
+	// code in the signed value still scopes the cookie
+	path := s.prefix + "/" + code
+	if l.Signed {
+		path = s.prefix + "/"
+	}
+	cookie := &http.Cookie{
+		Name:     accessCookie(code),
+		Value:    signed.Sign(s.derivedKey("access cookie"), code, time.Now().Add(s.cfg.Security.AccessDuration)),
+		Path:     path,
+		MaxAge:   int(s.cfg.Security.AccessDuration.Seconds()),
+		HttpOnly: true,
+	}
+	http.SetCookie(c.Writer, cookie)
+	c.Request.AddCookie(cookie)
+	return true
+}
+
+// renderPassword shows the password prompt, JSON clients
+// only get the message
+func renderPassword(c *gin.Context, status int, message string) {
+	if wantsJSON(c) {
+		if message == "" {
+			message = errPasswordRequired.Error()
+		}
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+	renderPage(c, status, "password.html", gin.H{
+		"error": translate(locale(c), message),
+	})
+}
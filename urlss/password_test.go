@@ -0,0 +1,130 @@
+package urlss
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestPasswordHash(t *testing.T) {
+	hash := hashPassword("hunter2")
+	if !checkPassword("hunter2", hash) || checkPassword("hunter3", hash) {
+		t.Error("checkPassword is weird")
+	}
+	if hashPassword("hunter2") == hash {
+		t.Error("Hashes should be salted")
+	}
+}
+
+func TestProtectedLink(t *testing.T) {
+	s := newTestServer(t, nil)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/api/links", strings.NewReader(`{"url":"https://intranet.example.com","password":"hunter2"}`)))
+	var created apiLink
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if !created.Protected || strings.Contains(w.Body.String(), "pbkdf2") || created.URL != "" {
+		t.Fatalf("Got %s", w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("PATCH", "/api/links/"+created.Code, strings.NewReader(`{"password":""}`)))
+	if w.Code != 401 {
+		t.Errorf("Anonymous clients should not remove the password, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, apiRequest("GET", "/api/links/"+created.Code, nil))
+	if !strings.Contains(w.Body.String(), "intranet.example.com") {
+		t.Errorf("The token should see the URL, got %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/"+created.Code, nil))
+	if w.Code != 401 || !strings.Contains(w.Body.String(), `type="password"`) {
+		t.Errorf("Got %d instead of the prompt", w.Code)
+	}
+	if _, redirect, _ := s.ShortenURL(created.Code); redirect {
+		t.Error("Protected links should not redirect without a password")
+	}
+
+	post := func(password, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/"+created.Code, strings.NewReader("password="+url.QueryEscape(password)))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w
+	}
+	w = post("hunter2", "192.0.2.10:1234")
+	if w.Code != 302 || w.Header().Get("Cache-Control") != "private, no-store" || w.Header().Get("Location") != "https://intranet.example.com" {
+		t.Fatalf("Got %d to %s", w.Code, w.Header().Get("Location"))
+	}
+	cookies := w.Result().Cookies()
+	req := httptest.NewRequest("GET", "/"+created.Code, nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if w.Code != 302 {
+		t.Errorf("The cookie should skip the prompt, got %d", w.Code)
+	}
+	cookies[0].Value = "9999999999.forged"
+	req = httptest.NewRequest("GET", "/"+created.Code, nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if w.Code != 401 {
+		t.Errorf("A forged cookie should not skip the prompt, got %d", w.Code)
+	}
+
+	for i := 1; i < s.cfg.Security.PasswordAttempts; i++ {
+		if w = post("wrong", "192.0.2.20:1234"); w.Code != 401 {
+			t.Errorf("Attempt %d got %d", i, w.Code)
+		}
+	}
+	if w = post("wrong", "192.0.2.20:1234"); w.Code != 429 {
+		t.Errorf("Should be throttled, got %d", w.Code)
+	}
+	if w = post("hunter2", "192.0.2.20:1234"); w.Code != 429 {
+		t.Errorf("Should stay throttled, got %d", w.Code)
+	}
+	if w = post("hunter2", "192.0.2.30:1234"); w.Code != 302 {
+		t.Errorf("Other addresses should not be throttled, got %d", w.Code)
+	}
+
+	// without a trusted proxy, X-Forwarded-For does not give new attempts
+	for i := 1; i <= s.cfg.Security.PasswordAttempts; i++ {
+		req := httptest.NewRequest("POST", "/"+created.Code, strings.NewReader("password=wrong"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i))
+		req.RemoteAddr = "192.0.2.40:1234"
+		w = httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+	}
+	if w.Code != 429 {
+		t.Errorf("Spoofed addresses should be throttled together, got %d", w.Code)
+	}
+}
+
+func TestAttemptsBehindProxy(t *testing.T) {
+	s := newTestServer(t, func(cfg *Config) { cfg.TrustProxy = true })
+	code, _ := s.AddLink(Link{URL: "https://intranet.example.com", PasswordHash: hashPassword("hunter2")})
+	post := func(forwarded string) int {
+		req := httptest.NewRequest("POST", "/"+code, strings.NewReader("password=wrong"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Forwarded-For", forwarded)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w.Code
+	}
+	// the proxy appends the address it saw to what the client sent
+	for i := 1; i < s.cfg.Security.PasswordAttempts; i++ {
+		post(fmt.Sprintf("198.51.100.%d, 203.0.113.9", i))
+	}
+	if status := post("198.51.100.99, 203.0.113.9"); status != 429 {
+		t.Errorf("Entries sent by the client should not give new attempts, got %d", status)
+	}
+	if status := post("203.0.113.9, 203.0.113.10"); status != 401 {
+		t.Errorf("Other addresses should not be throttled, got %d", status)
+	}
+}
@@ -0,0 +1,149 @@
+package urlss
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/schollz/urlss/qrcode"
+)
+
+// suffixRegexp matches the QR code (/code.png, /code.svg)
+// and stats page (/code+) of a short code
+var suffixRegexp = regexp.MustCompile(`^([A-Za-z0-9_-]+)(\.png|\.svg|\+)$`)
+
+const (
+	qrDefaultSize = 256
+	qrMaxSize     = 2048
+	qrMaxMargin   = 16
+	// qrCacheSize is how many rendered codes are kept
+	qrCacheSize = 1000
+)
+
+// qrCache holds rendered codes by their content and options
+var qrCache = struct {
+	sync.Mutex
+	images map[string][]byte
+}{images: make(map[string][]byte)}
+
+// baseURL is the configured base URL, or else the scheme
+// and host the request was made to
+func (s *Server) baseURL(r *http.Request) string {
+	if s.base != "" {
+		return s.base
+	}
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + s.prefix
+}
+
+// parseBaseURL checks the -base flag and drops its trailing slash
+func parseBaseURL(base string) (string, error) {
+	if base == "" {
+		return "", nil
+	}
+	u, err := url.Parse(base)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("base URL %q must look like https://urls.example.com", base)
+	}
+	return strings.TrimSuffix(base, "/"), nil
+}
+
+// intParam reads an integer query parameter within [min, max]
+func intParam(c *gin.Context, key string, def, min, max int) (int, error) {
+	s := c.Query(key)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%s must be between %d and %d", key, min, max)
+	}
+	return n, nil
+}
+
+// handleQR renders the QR code of the full short URL of code as a
+// png or svg, sized by ?size= in pixels, with the error correction
+// ?level= (L, M, Q or H) and a quiet zone of ?margin= modules
+func (s *Server) handleQR(c *gin.Context, code, format string) {
+	if _, err := s.Lookup(code); err != nil {
+		renderError(c, notFoundError(code))
+		return
+	}
+	size, err := intParam(c, "size", qrDefaultSize, 21, qrMaxSize)
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	margin, err := intParam(c, "margin", 4, 0, qrMaxMargin)
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	level, err := qrcode.ParseLevel(c.DefaultQuery("level", "M"))
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	content := s.baseURL(c.Request) + "/" + code
+	key := fmt.Sprintf("%s %d %d %d %s", format, size, margin, level, content)
+
+	qrCache.Lock()
+	image, ok := qrCache.images[key]
+	qrCache.Unlock()
+	if !ok {
+		qr, err := qrcode.Encode([]byte(content), level)
+		if err != nil {
+			renderError(c, err)
+			return
+		}
+		if format == "svg" {
+			image = qr.SVG(size, margin)
+		} else {
+			scale := size / (qr.Size + 2*margin)
+			if scale < 1 {
+				scale = 1
+			}
+			image = qr.PNG(scale, margin)
+		}
+		qrCache.Lock()
+		if len(qrCache.images) >= qrCacheSize {
+			qrCache.images = make(map[string][]byte)
+		}
+		qrCache.images[key] = image
+		qrCache.Unlock()
+	}
+	contentType := "image/png"
+	if format == "svg" {
+		contentType = "image/svg+xml"
+	}
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(http.StatusOK, contentType, image)
+}
+
+// handleStatsPage shows the clicks and QR code of a link, the
+// destination is left out for protected and signed links
+func (s *Server) handleStatsPage(c *gin.Context, code string) {
+	l, err := s.Lookup(code)
+	if err != nil {
+		renderError(c, notFoundError(code))
+		return
+	}
+	destination := l.URL
+	if l.hidesDestinations() {
+		destination = ""
+	}
+	renderPage(c, http.StatusOK, "stats.html", gin.H{
+		"code":        code,
+		"short":       s.baseURL(c.Request) + "/" + code,
+		"destination": destination,
+		"stats":       s.Stats(code),
+	})
+}
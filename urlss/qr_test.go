@@ -0,0 +1,95 @@
+package urlss
+
+import (
+	"bytes"
+	"image/png"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestQREndpoints(t *testing.T) {
+	s := newTestServer(t, nil)
+	code, err := s.Shorten("https://example.com/printed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/"+code+".png?size=300&level=H&margin=2", nil))
+	if w.Code != 200 || w.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("Got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if _, err := png.Decode(bytes.NewReader(w.Body.Bytes())); err != nil {
+		t.Error(err)
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/"+code+".svg", nil))
+	if w.Code != 200 || !strings.HasPrefix(w.Body.String(), "<svg") {
+		t.Errorf("Got %d %s", w.Code, w.Body.String())
+	}
+	cached := len(qrCache.images)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/"+code+".svg", nil))
+	if len(qrCache.images) != cached {
+		t.Error("Second request should be cached")
+	}
+
+	for _, bad := range []string{"?level=X", "?size=99999", "?margin=-1"} {
+		w = httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/"+code+".png"+bad, nil))
+		if w.Code != 400 {
+			t.Errorf("Got %d for %s", w.Code, bad)
+		}
+	}
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/nosuchcodehere.png", nil))
+	if w.Code != 404 {
+		t.Errorf("Got %d for a missing code", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/"+code+"+", nil))
+	if !strings.Contains(w.Body.String(), "http://example.com/"+code) || !strings.Contains(w.Body.String(), code+".svg") {
+		t.Errorf("Stats page is missing the link or QR code: %s", w.Body.String())
+	}
+}
+
+func TestParseBaseURL(t *testing.T) {
+	for base, want := range map[string]string{
+		"":                          "",
+		"https://urls.example.com/": "https://urls.example.com",
+		"http://example.com/s":      "http://example.com/s",
+	} {
+		if got, err := parseBaseURL(base); err != nil || got != want {
+			t.Errorf("%q: got %q %v", base, got, err)
+		}
+	}
+	for _, base := range []string{"urls.example.com", "ftp://example.com", "https://example.com/?a=1"} {
+		if _, err := parseBaseURL(base); err == nil {
+			t.Errorf("%q should fail", base)
+		}
+	}
+}
+
+func TestResultPage(t *testing.T) {
+	s := newTestServer(t, func(cfg *Config) {
+		cfg.BaseURL = "https://urls.example.com/"
+	})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/", strings.NewReader("url=https://example.com/result"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	s.ServeHTTP(w, req)
+	code, err := s.Shorten("https://example.com/result")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, `href="https://urls.example.com/`+code+`">https://urls.example.com/`+code+`</a>`) {
+		t.Errorf("Short link not rendered: %s", body)
+	}
+	if strings.Contains(body, "document.write") || !strings.Contains(body, `<form method="post" action="/">`) {
+		t.Error("Page should work without JavaScript")
+	}
+}
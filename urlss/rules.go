@@ -0,0 +1,123 @@
+package urlss
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+
+	"golang.org/x/text/language"
+)
+
+// Rule sends requests that match all of its conditions to its own URL
+type Rule struct {
+	// Platform is one of ios, android, windows, macos, linux,
+	// or mobile and desktop for any of the former
+	Platform string `json:"platform,omitempty"`
+	// Language is a BCP 47 tag matched against the most preferred
+	// Accept-Language, "de" matches de-CH but "pt-BR" not pt-PT
+	Language string `json:"language,omitempty"`
+	URL      string `json:"url"`
+}
+
+// platformRegexps are checked in order against the User-Agent,
+// iPads and Android tablets count as mobile
+var platformRegexps = []struct {
+	platform string
+	re       *regexp.Regexp
+}{
+	{"ios", regexp.MustCompile(`(?i)iphone|ipad|ipod`)},
+	{"android", regexp.MustCompile(`(?i)android`)},
+	{"windows", regexp.MustCompile(`(?i)windows`)},
+	{"macos", regexp.MustCompile(`(?i)macintosh|mac os x`)},
+	{"linux", regexp.MustCompile(`(?i)linux|x11|cros`)},
+}
+
+var mobilePlatforms = map[string]bool{"ios": true, "android": true}
+
+// platform returns the operating system of a User-Agent, or "" if unknown
+func platform(userAgent string) string {
+	for _, p := range platformRegexps {
+		if p.re.MatchString(userAgent) {
+			return p.platform
+		}
+	}
+	return ""
+}
+
+// matchesPlatform reports whether a User-Agent is on a rule's platform
+func matchesPlatform(rulePlatform, userAgent string) bool {
+	p := platform(userAgent)
+	switch rulePlatform {
+	case "":
+		return true
+	case "mobile":
+		return mobilePlatforms[p]
+	case "desktop":
+		return p != "" && !mobilePlatforms[p]
+	}
+	return rulePlatform == p
+}
+
+// matchesLanguage reports whether the most preferred language
+// of an Accept-Language header is or falls under ruleLanguage
+func matchesLanguage(ruleLanguage, acceptLanguage string) bool {
+	if ruleLanguage == "" {
+		return true
+	}
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return false
+	}
+	want := language.Make(ruleLanguage)
+	for tag := tags[0]; ; tag = tag.Parent() {
+		if tag == want {
+			return true
+		}
+		if tag.IsRoot() {
+			return false
+		}
+	}
+}
+
+var rulePlatforms = map[string]bool{
+	"":        true,
+	"mobile":  true,
+	"desktop": true,
+	"ios":     true,
+	"android": true,
+	"windows": true,
+	"macos":   true,
+	"linux":   true,
+}
+
+// validate checks a rule and makes its URL canonical
+func (r *Rule) validate(p urlPolicy) (err error) {
+	if !rulePlatforms[r.Platform] {
+		return errors.New("Unknown platform " + r.Platform)
+	}
+	if r.Language != "" {
+		if _, err = language.Parse(r.Language); err != nil {
+			return errors.New("Unknown language " + r.Language)
+		}
+	}
+	if r.Platform == "" && r.Language == "" {
+		return errors.New("Rules need a platform or a language")
+	}
+	r.URL, err = p.canonicalURL(r.URL)
+	return
+}
+
+// route returns the URL of the first rule matching the request
+// and its index, or the link's URL and -1 if none match
+func (l Link) route(r *http.Request) (string, int) {
+	if r == nil {
+		return l.URL, -1
+	}
+	for i, rule := range l.Rules {
+		if matchesPlatform(rule.Platform, r.UserAgent()) &&
+			matchesLanguage(rule.Language, r.Header.Get("Accept-Language")) {
+			return rule.URL, i
+		}
+	}
+	return l.URL, -1
+}
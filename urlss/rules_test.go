@@ -0,0 +1,91 @@
+package urlss
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	iPhoneAgent  = "Mozilla/5.0 (iPhone; CPU iPhone OS 11_0 like Mac OS X) AppleWebKit/604.1.38 (KHTML, like Gecko) Version/11.0 Mobile/15A372 Safari/604.1"
+	androidAgent = "Mozilla/5.0 (Linux; Android 8.0.0; Pixel XL Build/OPR6.170623.012) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/61.0.3163.98 Mobile Safari/537.36"
+	windowsAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/61.0.3163.100 Safari/537.36"
+)
+
+func TestMatches(t *testing.T) {
+	for userAgent, p := range map[string]string{
+		iPhoneAgent:  "ios",
+		androidAgent: "android",
+		windowsAgent: "windows",
+		"curl/7.55":  "",
+	} {
+		if got := platform(userAgent); got != p {
+			t.Errorf("Got %s instead of %s for %s", got, p, userAgent)
+		}
+	}
+	if !matchesPlatform("mobile", androidAgent) || matchesPlatform("desktop", iPhoneAgent) || !matchesPlatform("desktop", windowsAgent) {
+		t.Error("matchesPlatform is weird")
+	}
+
+	for _, test := range []struct {
+		rule, header string
+		match        bool
+	}{
+		{"de", "de-CH,de;q=0.9,en;q=0.8", true},
+		{"de", "en-US,de;q=0.9", false},
+		{"pt-BR", "pt-PT", false},
+		{"pt-BR", "pt-BR,pt;q=0.5", true},
+		{"fr", "", false},
+	} {
+		if matchesLanguage(test.rule, test.header) != test.match {
+			t.Errorf("%s against %s should be %v", test.rule, test.header, test.match)
+		}
+	}
+}
+
+func TestRouteEndpoint(t *testing.T) {
+	s := newTestServer(t, nil)
+	code, err := s.AddLink(Link{
+		URL: "https://example.com/app",
+		Rules: []Rule{
+			{Platform: "ios", URL: "https://apps.apple.com/app/id1"},
+			{Platform: "android", URL: "https://play.google.com/store/apps/details?id=com.example"},
+			{Language: "de", URL: "https://example.com/de/app"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		headers     string
+		destination string
+	}{
+		{`{"User-Agent":"` + iPhoneAgent + `"}`, "https://apps.apple.com/app/id1"},
+		{`{"User-Agent":"` + androidAgent + `","Accept-Language":"de"}`, "https://play.google.com/store/apps/details?id=com.example"},
+		{`{"User-Agent":"` + windowsAgent + `","Accept-Language":"de-AT"}`, "https://example.com/de/app"},
+		{`{"User-Agent":"` + windowsAgent + `"}`, "https://example.com/app"},
+	} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, apiRequest("POST", "/api/links/"+code+"/route", strings.NewReader(`{"headers":`+test.headers+`}`)))
+		var route struct {
+			Destination string
+		}
+		json.Unmarshal(w.Body.Bytes(), &route)
+		if route.Destination != test.destination {
+			t.Errorf("Got %s instead of %s for %s", route.Destination, test.destination, test.headers)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/"+code, nil)
+	req.Header.Set("User-Agent", iPhoneAgent)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if w.Header().Get("Location") != "https://apps.apple.com/app/id1" {
+		t.Errorf("Got %s", w.Header().Get("Location"))
+	}
+
+	if _, err = s.AddLink(Link{URL: "https://example.com", Rules: []Rule{{Platform: "palm", URL: "https://example.com/palm"}}}); err == nil {
+		t.Error("Should refuse unknown platforms")
+	}
+}
@@ -0,0 +1,92 @@
+package urlss
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// ScheduleEntry sends a link to URL from Start on, until the next entry
+type ScheduleEntry struct {
+	// Start is RFC 3339, or "2006-01-02 15:04" in the link's Timezone
+	Start string `json:"start"`
+	URL   string `json:"url"`
+}
+
+// scheduleLayouts are the accepted formats of a start without an offset
+var scheduleLayouts = []string{"2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02T15:04:05"}
+
+// now is replaced in tests
+var now = time.Now
+
+// startTime parses the start of an entry in the zone loc
+func (e ScheduleEntry) startTime(loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, e.Start); err == nil {
+		return t, nil
+	}
+	for _, layout := range scheduleLayouts {
+		if t, err := time.ParseInLocation(layout, e.Start, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("Could not read start time " + e.Start)
+}
+
+// location is the time zone of a link's schedule, UTC by default
+func (l Link) location() (*time.Location, error) {
+	if l.Timezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(l.Timezone)
+	if err != nil {
+		return nil, errors.New("Unknown time zone " + l.Timezone)
+	}
+	return loc, nil
+}
+
+// validateSchedule checks the schedule of a link, makes its URLs
+// canonical and sorts it by start time
+func (l *Link) validateSchedule(p urlPolicy) (err error) {
+	if len(l.Schedule) == 0 {
+		l.Schedule = nil
+	}
+	loc, err := l.location()
+	if err != nil {
+		return
+	}
+	starts := make(map[string]time.Time)
+	for i := range l.Schedule {
+		if starts[l.Schedule[i].Start], err = l.Schedule[i].startTime(loc); err != nil {
+			return
+		}
+		if l.Schedule[i].URL, err = p.canonicalURL(l.Schedule[i].URL); err != nil {
+			return
+		}
+	}
+	sort.SliceStable(l.Schedule, func(i, j int) bool {
+		return starts[l.Schedule[i].Start].Before(starts[l.Schedule[j].Start])
+	})
+	return nil
+}
+
+// scheduled returns the index of the entry active at t and of the
+// one after it, either is -1 if there is none
+func (l Link) scheduled(t time.Time) (active, next int) {
+	active, next = -1, -1
+	loc, err := l.location()
+	if err != nil {
+		return
+	}
+	for i, e := range l.Schedule {
+		start, err := e.startTime(loc)
+		if err != nil {
+			continue
+		}
+		if start.After(t) {
+			next = i
+			return
+		}
+		active = i
+	}
+	return
+}
@@ -0,0 +1,67 @@
+package urlss
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSchedule(t *testing.T) {
+	s := newTestServer(t, nil)
+	defer func() { now = time.Now }()
+	code, err := s.AddLink(Link{
+		URL:      "https://example.com/register",
+		Timezone: "Europe/Berlin",
+		Schedule: []ScheduleEntry{
+			{Start: "2026-06-02 10:00", URL: "https://example.com/recording"},
+			{Start: "2026-06-01T09:00:00+02:00", URL: "https://example.com/live"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, _ := s.Lookup(code)
+	if l.Schedule[0].URL != "https://example.com/live" {
+		t.Errorf("Schedule should be sorted, got %+v", l.Schedule)
+	}
+
+	for at, destination := range map[string]string{
+		"2026-06-01T06:59:00Z": "https://example.com/register",
+		"2026-06-01T07:00:00Z": "https://example.com/live",
+		"2026-06-02T07:59:00Z": "https://example.com/live",
+		"2026-06-02T08:00:00Z": "https://example.com/recording",
+	} {
+		t0, _ := time.Parse(time.RFC3339, at)
+		now = func() time.Time { return t0 }
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/"+code, nil))
+		if w.Header().Get("Location") != destination {
+			t.Errorf("At %s got %s instead of %s", at, w.Header().Get("Location"), destination)
+		}
+		if w.Code != 302 || w.Header().Get("Cache-Control") != "private, no-store" {
+			t.Errorf("Scheduled links should not be cached, got %d %s", w.Code, w.Header())
+		}
+	}
+
+	t0, _ := time.Parse(time.RFC3339, "2026-06-01T12:00:00Z")
+	now = func() time.Time { return t0 }
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, apiRequest("GET", "/api/links/"+code+"/schedule", nil))
+	var view struct {
+		URL    string
+		Active *ScheduleEntry
+		Next   *ScheduleEntry
+	}
+	json.Unmarshal(w.Body.Bytes(), &view)
+	if view.URL != "https://example.com/live" || view.Active == nil || view.Next == nil || view.Next.URL != "https://example.com/recording" {
+		t.Errorf("Got %s", w.Body.String())
+	}
+
+	if _, err := s.AddLink(Link{URL: "https://example.com", Timezone: "Mars/Olympus"}); err == nil {
+		t.Error("Should refuse unknown time zones")
+	}
+	if _, err := s.AddLink(Link{URL: "https://example.com", Schedule: []ScheduleEntry{{Start: "tomorrow", URL: "https://example.com/b"}}}); err == nil {
+		t.Error("Should refuse unreadable start times")
+	}
+}
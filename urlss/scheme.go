@@ -0,0 +1,170 @@
+package urlss
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/goware/urlx"
+)
+
+// blockedSchemes can run code or read local data in the browser
+// and are refused even when listed in -schemes
+var blockedSchemes = map[string]bool{
+	"javascript": true,
+	"vbscript":   true,
+	"data":       true,
+	"file":       true,
+	"blob":       true,
+	"about":      true,
+}
+
+// webSchemes are parsed and normalized by urlx
+var webSchemes = map[string]bool{
+	"http":  true,
+	"https": true,
+	"ftp":   true,
+}
+
+// schemeValidators check the schemes that have a known syntax, other
+// allowed schemes (app deep links such as slack://) only need a body
+var schemeValidators = map[string]func(u *url.URL) error{
+	"mailto": func(u *url.URL) error {
+		for _, address := range strings.Split(u.Opaque, ",") {
+			at := strings.Index(address, "@")
+			if at < 1 || at == len(address)-1 {
+				return errors.New("mailto needs an email address")
+			}
+		}
+		return nil
+	},
+	"tel": validatePhone,
+	"sms": validatePhone,
+	"magnet": func(u *url.URL) error {
+		if !strings.HasPrefix(u.Query().Get("xt"), "urn:") {
+			return errors.New("magnet needs an xt=urn: parameter")
+		}
+		return nil
+	},
+	"ssh": func(u *url.URL) error {
+		host, _, err := urlx.SplitHostPort(u)
+		if err != nil {
+			return err
+		}
+		if host == "" {
+			return errors.New("ssh needs a host")
+		}
+		return nil
+	},
+}
+
+var phoneRegexp = regexp.MustCompile(`^\+?[0-9][0-9()./ -]*$`)
+
+func validatePhone(u *url.URL) error {
+	number, err := url.PathUnescape(u.Opaque)
+	if err != nil || !phoneRegexp.MatchString(number) {
+		return errorf("%s needs a phone number", u.Scheme)
+	}
+	return nil
+}
+
+const defaultSchemes = "http,https,ftp,mailto,tel,sms,magnet,ssh"
+
+// urlPolicy decides which URLs can be shortened and how they are written
+type urlPolicy struct {
+	normalize NormalizePolicy
+	// schemes are the schemes that can be shortened
+	schemes map[string]bool
+}
+
+// parseSchemes reads the comma separated -schemes flag
+func parseSchemes(list string) (map[string]bool, error) {
+	schemes := make(map[string]bool)
+	for _, scheme := range splitList(list) {
+		scheme = strings.ToLower(scheme)
+		if blockedSchemes[scheme] {
+			return nil, fmt.Errorf("scheme %s can not be allowed", scheme)
+		}
+		schemes[scheme] = true
+	}
+	return schemes, nil
+}
+
+var schemeRegexp = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9+.-]*):(.*)$`)
+
+// urlScheme returns the lowercased scheme of rawURL, or "" if it has
+// none. "example.com:8080" and "localhost:8080" are a host and a port.
+func urlScheme(rawURL string) string {
+	m := schemeRegexp.FindStringSubmatch(rawURL)
+	if m == nil {
+		return ""
+	}
+	scheme := strings.ToLower(m[1])
+	if !strings.HasPrefix(m[2], "//") && (strings.Contains(scheme, ".") || scheme == "localhost") {
+		return ""
+	}
+	return scheme
+}
+
+// opaqueSchemes are written without "//"
+var opaqueSchemes = map[string]bool{
+	"mailto": true,
+	"tel":    true,
+	"sms":    true,
+	"magnet": true,
+}
+
+// hasAuthority reports whether an allowed scheme is written with "//"
+func (p urlPolicy) hasAuthority(scheme string) bool {
+	return p.schemes[scheme] && !opaqueSchemes[scheme]
+}
+
+// canonicalURL checks rawURL against the scheme allowlist and returns
+// the string used to deduplicate and redirect to it
+func (p urlPolicy) canonicalURL(rawURL string) (string, error) {
+	scheme := urlScheme(rawURL)
+	if blockedSchemes[scheme] || (scheme != "" && !p.schemes[scheme]) {
+		return "", blockedError(errorf("Scheme %s is not allowed", scheme))
+	}
+	if scheme == "" || webSchemes[scheme] {
+		parsedURL, err := urlx.Parse(rawURL)
+		if err != nil {
+			return "", invalidURLError(errorf("Invalid URL %s", rawURL))
+		}
+		normalized, err := p.normalize.Normalize(parsedURL)
+		if err != nil {
+			return "", invalidURLError(errorf("Invalid URL %s", rawURL))
+		}
+		return normalized, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", invalidURLError(errorf("Invalid URL %s", rawURL))
+	}
+	u.Scheme = scheme
+	if validate, ok := schemeValidators[scheme]; ok {
+		err = validate(u)
+	} else if u.Opaque == "" && u.Host == "" && u.Path == "" {
+		err = errorf("%s link is empty", scheme)
+	}
+	if err != nil {
+		return "", invalidURLError(errorf("Invalid URL %s: %v", rawURL, err))
+	}
+	return u.String(), nil
+}
+
+// redirectCode is permanent for web URLs and temporary for other
+// schemes by default, so browsers don't cache a handoff to another
+// application. Temporary redirects are always 302 Found.
+func (s *Server) redirectCode(destination string, temporary bool) int {
+	if temporary {
+		return http.StatusFound
+	}
+	if webSchemes[urlScheme(destination)] {
+		return s.cfg.Redirect.Web
+	}
+	return s.cfg.Redirect.Other
+}
@@ -0,0 +1,65 @@
+package urlss
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCanonicalURL(t *testing.T) {
+	s := newTestServer(t, nil)
+	for rawURL, canonical := range map[string]string{
+		"example.com:8080/a":                  "http://example.com:8080/a",
+		"localhost:8080":                      "http://localhost:8080",
+		"HTTPS://Example.com":                 "https://example.com",
+		"mailto:someone@example.com":          "mailto:someone@example.com",
+		"tel:+1-555-0100":                     "tel:+1-555-0100",
+		"magnet:?xt=urn:btih:abcdef&dn=file":  "magnet:?xt=urn:btih:abcdef&dn=file",
+		"ssh://git@example.com:2222/repo.git": "ssh://git@example.com:2222/repo.git",
+	} {
+		got, err := s.policy.canonicalURL(rawURL)
+		if err != nil {
+			t.Errorf("%s: %s", rawURL, err)
+		} else if got != canonical {
+			t.Errorf("%s: expected %s, got %s", rawURL, canonical, got)
+		}
+	}
+	for _, rawURL := range []string{
+		"javascript:alert(1)",
+		"JavaScript:alert(1)",
+		"data:text/html,hi",
+		"mailto:nobody",
+		"tel:call-me",
+		"magnet:?dn=file",
+		"slack://open",
+	} {
+		if _, err := s.policy.canonicalURL(rawURL); err == nil {
+			t.Errorf("%s should not be allowed", rawURL)
+		}
+	}
+
+	if _, err := parseSchemes("http,javascript"); err == nil {
+		t.Error("Should refuse to allow javascript")
+	}
+	s = newTestServer(t, func(cfg *Config) {
+		cfg.Schemes = "http,https,slack"
+	})
+	if got, err := s.policy.canonicalURL("slack://open?team=T1"); err != nil || got != "slack://open?team=T1" {
+		t.Errorf("Got %s, %v for slack link", got, err)
+	}
+	if got := s.policy.pathTarget(httptest.NewRequest("GET", "/slack:/open", nil)); got != "slack://open" {
+		t.Errorf("Got %s for merged slack scheme", got)
+	}
+}
+
+func TestRedirectNonHTTP(t *testing.T) {
+	s := newTestServer(t, nil)
+	shortened, err := s.Shorten("mailto:someone@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/"+shortened, nil))
+	if w.Code != 302 || w.Header().Get("Location") != "mailto:someone@example.com" {
+		t.Errorf("Got %d to %s", w.Code, w.Header().Get("Location"))
+	}
+}
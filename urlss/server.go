@@ -0,0 +1,149 @@
+// Package urlss is a URL shortener that can be embedded in other
+// programs. A Server shortens and resolves URLs in its own store and
+// is an http.Handler that serves the web pages and the API:
+//
+//	cfg := urlss.DefaultConfig()
+//	cfg.Prefix = "/s"
+//	s, err := urlss.NewServer(cfg, urlss.OpenStore(cfg.Data), nil)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	mux.Handle("/s/", s)
+//	code, err := s.Shorten("https://example.com/a/long/page")
+package urlss
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/schollz/jsonstore"
+)
+
+// Server is a URL shortener with its own store, settings and
+// state, so that several can run in one process
+type Server struct {
+	cfg    Config
+	store  *jsonstore.JSONStore
+	log    *Logger
+	router *gin.Engine
+	// routes maps the handlers to the paths they are registered at
+	routes map[string]string
+
+	// base is the base URL of short links without a trailing slash
+	base string
+	// prefix is the path the server is mounted at, without a trailing slash
+	prefix string
+	// secret signs links and the access cookies of protected links
+	secret []byte
+	policy urlPolicy
+
+	// fileMu serializes changes to the store, file is the
+	// version of the data file the store was last synced with
+	fileMu sync.Mutex
+	file   fileState
+	// saveErr is why the last change could not be saved, and
+	// closed refuses changes once Close waited for them
+	saveErr error
+	closed  bool
+	// ready is 1 once the store is loaded and until Close,
+	// loadErr is why it could not be loaded
+	ready    int32
+	loadErr  error
+	metrics  metrics
+	rotation *rotation
+	attempts attempts
+	// clicks are counted in memory and saved every
+	// cfg.FlushInterval until stopFlush is closed
+	clicks    clickCounts
+	stopFlush chan struct{}
+	stopOnce  sync.Once
+}
+
+// NewServer checks cfg and makes a server for the links in store,
+// which is saved to cfg.Data. A nil store is loaded from cfg.Data
+// in the background, and until it is the server only answers its
+// probes. A nil logger logs to stderr as cfg.Log says.
+func NewServer(cfg Config, store *jsonstore.JSONStore, logger *Logger) (*Server, error) {
+	policy, err := cfg.validate()
+	if err != nil {
+		return nil, err
+	}
+	if logger == nil {
+		logger = NewLogger(os.Stderr, cfg.Log)
+	}
+	loaded := store != nil
+	if !loaded {
+		store = new(jsonstore.JSONStore)
+	}
+	s := &Server{
+		cfg:       cfg,
+		store:     store,
+		log:       logger,
+		base:      strings.TrimSuffix(cfg.BaseURL, "/"),
+		prefix:    strings.TrimSuffix(cfg.Prefix, "/"),
+		secret:    []byte(cfg.Secret),
+		policy:    policy,
+		rotation:  newRotation(),
+		attempts:  attempts{failed: make(map[string][]time.Time)},
+		clicks:    clickCounts{pending: make(map[string]*Stats)},
+		stopFlush: make(chan struct{}),
+	}
+	if cfg.Secret == "" {
+		s.secret = randomBytes(32)
+	}
+	if s.router, err = s.setupRouter(); err != nil {
+		return nil, err
+	}
+	if loaded {
+		s.file = statFile(cfg.Data)
+		s.ready = 1
+	} else {
+		go s.load()
+	}
+	go s.flushEvery(cfg.FlushInterval)
+	return s, nil
+}
+
+// ServeHTTP makes the server an http.Handler. Under a prefix it
+// expects the full path, so it is mounted without http.StripPrefix.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.prefix != "" {
+		path := strings.TrimPrefix(r.URL.Path, s.prefix)
+		if len(path) == len(r.URL.Path) || (path != "" && path[0] != '/') {
+			http.NotFound(w, r)
+			return
+		}
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = "/" + strings.TrimPrefix(path, "/")
+		r2.URL.RawPath = ""
+		if rawPath := strings.TrimPrefix(r.URL.EscapedPath(), s.prefix); rawPath != r.URL.EscapedPath() {
+			r2.URL.RawPath = "/" + strings.TrimPrefix(rawPath, "/")
+		}
+		r = r2
+	}
+	s.router.ServeHTTP(w, r)
+}
+
+// Normalize returns the canonical form of rawURL that is stored
+// and compared, or why it can't be shortened
+func (s *Server) Normalize(rawURL string) (string, error) {
+	return s.policy.canonicalURL(rawURL)
+}
+
+// OpenStore loads the links saved at path, or starts
+// an empty store if there are none yet
+func OpenStore(path string) *jsonstore.JSONStore {
+	store, err := jsonstore.Open(path)
+	if err != nil {
+		return new(jsonstore.JSONStore)
+	}
+	return store
+}
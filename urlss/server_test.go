@@ -0,0 +1,234 @@
+package urlss
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/schollz/jsonstore"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestServer makes a server with an empty store saved in a
+// temporary directory, change edits the default configuration
+func newTestServer(t *testing.T, change func(*Config)) *Server {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "urlss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	cfg := DefaultConfig()
+	cfg.Data = filepath.Join(dir, "urls.json.gz")
+	cfg.APIToken = testToken
+	if change != nil {
+		change(&cfg)
+	}
+	s, err := NewServer(cfg, new(jsonstore.JSONStore), NewLogger(ioutil.Discard, LogConfig{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// testToken is the API token of test servers
+const testToken = "test-token"
+
+// apiRequest is a request to the API with the token
+func apiRequest(method, target string, body io.Reader) *http.Request {
+	r := httptest.NewRequest(method, target, body)
+	r.Header.Set("Authorization", "Bearer "+testToken)
+	return r
+}
+
+func TestServersAreIsolated(t *testing.T) {
+	a := newTestServer(t, nil)
+	b := newTestServer(t, nil)
+	code, err := a.Shorten("https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest("GET", "/"+code, nil))
+	if w.Code != http.StatusMovedPermanently {
+		t.Errorf("Got %d from the server with the link", w.Code)
+	}
+	w = httptest.NewRecorder()
+	b.ServeHTTP(w, httptest.NewRequest("GET", "/"+code, nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Got %d from the other server", w.Code)
+	}
+}
+
+func TestNewServerRejectsInvalidConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Redirect.Web = 200
+	if _, err := NewServer(cfg, new(jsonstore.JSONStore), nil); err == nil {
+		t.Error("An invalid configuration should not make a server")
+	}
+}
+
+func TestMountedUnderPrefix(t *testing.T) {
+	s := newTestServer(t, func(cfg *Config) {
+		cfg.Prefix = "/s/"
+		// a code of s would be the prefix without its slash
+		cfg.Codes.MinLength = 2
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/s/", s)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	form := url.Values{"url": {"https://example.com/page"}}
+	req := httptest.NewRequest("POST", "/s/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	code, err := s.Shorten("https://example.com/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := w.Body.String()
+	if w.Code != http.StatusOK || !strings.Contains(body, `href="http://example.com/s/`+code+`"`) || !strings.Contains(body, `action="/s/"`) {
+		t.Errorf("Got %d %s", w.Code, body)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/s/"+code, nil))
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "https://example.com/page" {
+		t.Errorf("Got %d to %s", w.Code, w.Header().Get("Location"))
+	}
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/"+code, nil))
+	if w.Code != http.StatusTeapot {
+		t.Errorf("Paths outside the prefix should be left to the mux, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/sx/"+code, nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Got %d outside the prefix", w.Code)
+	}
+}
+
+func TestSharedDataFile(t *testing.T) {
+	running := newTestServer(t, nil)
+	offline, err := NewServer(running.cfg, OpenStore(running.cfg.Data), NewLogger(ioutil.Discard, LogConfig{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := offline.Shorten("https://example.com/offline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	running.ServeHTTP(w, httptest.NewRequest("GET", "/"+code, nil))
+	if w.Header().Get("Location") != "https://example.com/offline" {
+		t.Fatalf("The running server should see new links, got %d", w.Code)
+	}
+
+	// neither server undoes the changes of the other
+	if _, err = offline.Shorten("https://example.com/second"); err != nil {
+		t.Fatal(err)
+	}
+	if err = offline.DeleteLink(code); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = running.Shorten("https://example.com/third"); err != nil {
+		t.Fatal(err)
+	}
+	reopened := OpenStore(running.cfg.Data)
+	var l Link
+	if reopened.Get(code, &l) == nil {
+		t.Error("The deleted link should stay deleted")
+	}
+	if len(reopened.Keys()) != 7 {
+		t.Errorf("Expected two links, their URLs and their stats and the deleted code, got %v", reopened.Keys())
+	}
+}
+
+func TestClose(t *testing.T) {
+	s := newTestServer(t, nil)
+	if _, err := s.Shorten("https://example.com/saved"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Shorten("https://example.com/late"); errorStatus(err) != http.StatusServiceUnavailable {
+		t.Errorf("Got %v after closing", err)
+	}
+	reopened := OpenStore(s.cfg.Data)
+	var code string
+	if reopened.Get("https://example.com/saved", &code) != nil {
+		t.Error("The link should be saved")
+	}
+	if leftover, _ := filepath.Glob(filepath.Join(filepath.Dir(s.cfg.Data), "*.tmp*")); len(leftover) > 0 {
+		t.Errorf("Got temporary files %v", leftover)
+	}
+
+	// a directory in the way of the temporary file fails the save
+	broken := newTestServer(t, nil)
+	os.MkdirAll(filepath.Join(filepath.Dir(broken.cfg.Data), "urls.json.tmp.gz", "x"), 0755)
+	if _, err := broken.Shorten("https://example.com/unsaved"); errorStatus(err) != http.StatusInternalServerError {
+		t.Errorf("Got %v for a change that was not saved", err)
+	}
+	w := httptest.NewRecorder()
+	broken.ServeHTTP(w, httptest.NewRequest("POST", "/api/links", strings.NewReader(`{"url":"https://example.com/unsaved"}`)))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Got %d for a change that was not saved", w.Code)
+	}
+	if err := broken.Close(); err == nil {
+		t.Error("Close should report that the last change was not saved")
+	}
+}
+
+func TestClicksAreFlushed(t *testing.T) {
+	s := newTestServer(t, func(cfg *Config) { cfg.FlushInterval = time.Hour })
+	code, err := s.Shorten("https://example.com/clicked")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := statFile(s.cfg.Data)
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/"+code, nil))
+	}
+	if statFile(s.cfg.Data) != saved {
+		t.Error("Redirects should not save the links")
+	}
+	if clicks := s.Stats(code).Clicks; clicks != 3 {
+		t.Errorf("Expected 3 clicks before they are saved, got %d", clicks)
+	}
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var stats Stats
+	OpenStore(s.cfg.Data).Get(statsKey(code), &stats)
+	if stats.Clicks != 3 || stats.LastClick.IsZero() {
+		t.Errorf("Close should save the clicks, got %+v", stats)
+	}
+
+	ticking := newTestServer(t, func(cfg *Config) { cfg.FlushInterval = 10 * time.Millisecond })
+	code, _ = ticking.Shorten("https://example.com/clicked")
+	ticking.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/"+code, nil))
+	for i := 0; i < 100 && stats.Clicks != 1; i++ {
+		time.Sleep(10 * time.Millisecond)
+		stats = Stats{}
+		OpenStore(ticking.cfg.Data).Get(statsKey(code), &stats)
+	}
+	if stats.Clicks != 1 {
+		t.Errorf("The clicks should be saved every flush_interval, got %+v", stats)
+	}
+}
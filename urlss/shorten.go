@@ -0,0 +1,283 @@
+package urlss
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/multitemplate"
+	"github.com/gin-gonic/gin"
+)
+
+// prefixKey holds the prefix of the server in the context of
+// its requests, for the links on the pages
+const prefixKey = "urlss_prefix"
+
+// setupRouter registers the creation endpoints and the
+// legacy path-style shortening and redirecting
+func (s *Server) setupRouter() (*gin.Engine, error) {
+	r := gin.New()
+	r.ForwardedByClientIP = s.cfg.TrustProxy
+	r.Use(s.logRequests, s.instrument)
+	r.Use(func(c *gin.Context) {
+		c.Set(prefixKey, s.prefix)
+		if probes[c.Request.URL.Path] {
+			return
+		}
+		if !s.isReady() {
+			c.Header("Retry-After", "1")
+			renderError(c, errNotReady)
+			c.Abort()
+			return
+		}
+		s.refresh()
+	})
+	if s.cfg.Dev {
+		r.HTMLRender = devRender{s.cfg.Templates}
+	} else {
+		render, err := loadTemplates(s.cfg.Templates, templateNames...)
+		if err != nil {
+			return nil, err
+		}
+		r.HTMLRender = render
+	}
+	if s.cfg.Static != "" {
+		r.Static("/static", s.cfg.Static)
+	}
+	r.GET("/healthz", s.handleHealth)
+	r.GET("/readyz", s.handleReady)
+	r.GET("/metrics", s.handleMetrics)
+	r.GET("/", s.handleIndex)
+	r.POST("/", s.handleCreate)
+	r.POST("/api/links", s.handleAPICreate)
+	r.GET("/api/links/:code/stats", s.handleAPIStats)
+	admin := r.Group("/api", s.requireToken)
+	admin.GET("/links", s.handleAPIList)
+	admin.GET("/export", s.handleAPIExport)
+	admin.POST("/import", s.handleAPIImport)
+	admin.GET("/links/:code", s.handleAPIGet)
+	admin.PATCH("/links/:code", s.handleAPIUpdate)
+	admin.DELETE("/links/:code", s.handleAPIDelete)
+	admin.POST("/links/:code/route", s.handleAPIRoute)
+	admin.GET("/links/:code/schedule", s.handleAPISchedule)
+	admin.POST("/links/:code/sign", s.handleAPISign)
+	r.GET("/favicon.ico", func(c *gin.Context) {
+		c.Status(http.StatusNotFound)
+	})
+	r.NoRoute(s.handleAction)
+	s.routes = map[string]string{}
+	for _, route := range r.Routes() {
+		s.routes[route.Method+" "+route.Handler] = route.Path
+	}
+	return r, nil
+}
+
+// handleIndex shows the form, or shortens the ?url= parameter
+func (s *Server) handleIndex(c *gin.Context) {
+	if rawURL := c.Query("url"); rawURL != "" {
+		shortened, err := s.Shorten(rawURL)
+		s.renderIndex(c, shortened, err)
+		return
+	}
+	s.renderIndex(c, "", nil)
+}
+
+// handleCreate shortens the url field of a form POST
+func (s *Server) handleCreate(c *gin.Context) {
+	shortened, err := s.Shorten(c.PostForm("url"))
+	s.renderIndex(c, shortened, err)
+}
+
+// handleAction performs the shortening or redirecting
+// of legacy path-style requests
+func (s *Server) handleAction(c *gin.Context) {
+	target := s.policy.pathTarget(c.Request)
+	switch c.Request.Method {
+	case "GET", "HEAD":
+		if m := suffixRegexp.FindStringSubmatch(c.Request.URL.Path[1:]); m != nil {
+			if m[2] == "+" {
+				s.handleStatsPage(c, m[1])
+			} else {
+				s.handleQR(c, m[1], m[2][1:])
+			}
+			return
+		}
+	case "POST":
+		// the password prompt posts back to the link
+		if !s.unlock(c, target) {
+			return
+		}
+	default:
+		return
+	}
+	shortened, redirect, temporary, err := s.shortenRequest(target, c.Writer, c.Request)
+	if redirect {
+		addLogFields(c, "destination", loggedURL(shortened))
+		if temporary {
+			c.Header("Cache-Control", "private, no-store")
+		}
+		c.Redirect(s.redirectCode(shortened, temporary), shortened)
+	} else if err == errPasswordRequired {
+		renderPassword(c, http.StatusUnauthorized, "")
+	} else {
+		s.renderIndex(c, shortened, err)
+	}
+}
+
+func (s *Server) renderIndex(c *gin.Context, shortened string, err error) {
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	short := ""
+	if shortened != "" {
+		addLogFields(c, "code", shortened)
+		short = s.baseURL(c.Request) + "/" + shortened
+	}
+	renderPage(c, http.StatusOK, "index.html", gin.H{
+		"shortened": shortened,
+		"short":     short,
+	})
+}
+
+// ShortenURL redirects requestURL if it starts with a short code
+// and shortens it otherwise
+func (s *Server) ShortenURL(requestURL string) (shortened string, redirect bool, err error) {
+	shortened, redirect, _, err = s.shortenRequest(requestURL, nil, nil)
+	return
+}
+
+// shortenRequest is ShortenURL for a request r, which decides
+// between the routing rules and variants of a link. Temporary
+// redirects must not be cached, see Link.temporary.
+func (s *Server) shortenRequest(requestURL string, w http.ResponseWriter, r *http.Request) (shortened string, redirect, temporary bool, err error) {
+	if requestURL == "" {
+		return
+	}
+	code, extra, viaToken, err := s.resolveCode(requestURL)
+	if err != nil {
+		return
+	}
+	if !viaToken && !isCode(code) {
+		shortened, err = s.Shorten(requestURL)
+		return
+	}
+	// Redirect the URL if it is shortened
+	link, err := s.Lookup(code)
+	if err == nil && link.Signed && !viaToken {
+		// signed links are only found through their tokens
+		err = notFoundError(requestURL)
+	}
+	if err == nil && link.PasswordHash != "" && !s.hasAccess(r, code) {
+		err = errPasswordRequired
+		return
+	}
+	variant := -1
+	if err == nil {
+		var target string
+		target, variant = link.target(code, s.prefix+"/"+code, w, r, s.rotation)
+		shortened, err = link.destination(target, extra)
+	}
+	if err == nil {
+		redirect = true
+		temporary = link.temporary()
+		s.metrics.inc(&s.metrics.redirects)
+		s.recordClick(code, variant)
+	} else {
+		s.metrics.inc(&s.metrics.misses)
+		err = notFoundError(requestURL)
+	}
+	return
+}
+
+// Shorten returns the short code for rawURL,
+// making a new one if it was never shortened
+func (s *Server) Shorten(rawURL string) (shortened string, err error) {
+	return s.AddLink(Link{URL: rawURL})
+}
+
+// From http://stackoverflow.com/questions/22892120/how-to-generate-a-random-string-of-a-fixed-length-in-golang
+const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+const (
+	letterIdxBits = 6                    // 6 bits to represent a letter index
+	letterIdxMask = 1<<letterIdxBits - 1 // All 1-bits, as many as letterIdxBits
+	letterIdxMax  = 63 / letterIdxBits   // # of letter indices fitting in 63 bits
+)
+
+var src = rand.NewSource(time.Now().UnixNano())
+
+// srcMu guards src, which servers share
+var srcMu sync.Mutex
+
+func RandString(n int) string {
+	return randomString(letterBytes, n)
+}
+
+// randomString is RandString drawing from an alphabet
+// of at most 64 characters
+func randomString(alphabet string, n int) string {
+	srcMu.Lock()
+	defer srcMu.Unlock()
+	b := make([]byte, n)
+	// A src.Int63() generates 63 random bits, enough for letterIdxMax characters!
+	for i, cache, remain := n-1, src.Int63(), letterIdxMax; i >= 0; {
+		if remain == 0 {
+			cache, remain = src.Int63(), letterIdxMax
+		}
+		if idx := int(cache & letterIdxMask); idx < len(alphabet) {
+			b[i] = alphabet[idx]
+			i--
+		}
+		cache >>= letterIdxBits
+		remain--
+	}
+
+	return string(b)
+}
+
+// NewCode generates a short code stochastically,
+// checking for collisions with links, deleted codes and
+// routes until it selects a free one
+func (s *Server) NewCode() string {
+	for n := s.cfg.Codes.MinLength; n <= s.cfg.Codes.MaxLength; n++ {
+		for i := 0; i < 10; i++ {
+			candidate := randomString(s.cfg.Codes.Alphabet, n)
+			var foo json.RawMessage
+			if s.store.Get(candidate, &foo) != nil && s.store.Get(deletedKey(candidate), &foo) != nil && !s.reservedCode(candidate) {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
+
+// reservedCode reports whether a code is the first segment of
+// a route, such as healthz or api, which would shadow its link
+func (s *Server) reservedCode(code string) bool {
+	for _, path := range s.routes {
+		if strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)[0] == code {
+			return true
+		}
+	}
+	return false
+}
+
+// loadTemplates will use the built-in assets, or their
+// overrides in dir, to load required templates
+func loadTemplates(dir string, list ...string) (multitemplate.Render, error) {
+	r := multitemplate.New()
+
+	for _, x := range list {
+		tmplMessage, err := readTemplate(dir, x)
+		if err != nil {
+			return nil, err
+		}
+
+		r.Add(x, tmplMessage)
+	}
+
+	return r, nil
+}
@@ -0,0 +1,53 @@
+package urlss
+
+import (
+	"testing"
+)
+
+func TestHandleAction(t *testing.T) {
+	srv := newTestServer(t, nil)
+	shortened, redirect, err := srv.ShortenURL("www.google.com")
+	if len(shortened) != 1 {
+		t.Errorf("Got %s for some reason", shortened)
+	}
+	if redirect == true {
+		t.Error("Incorrectly redirecting")
+	}
+	if err != nil {
+		t.Error(err)
+	}
+
+	s := srv.NewCode()
+	if s == shortened {
+		t.Error("New shortened URL should be different")
+	}
+
+	shortened, redirect, err = srv.ShortenURL(shortened)
+	if shortened != "http://www.google.com" {
+		t.Errorf("Got %s for some reason", shortened)
+	}
+	if redirect == false {
+		t.Error("Incorrectly NOT redirecting")
+	}
+	if err != nil {
+		t.Error(err)
+	}
+	shortened, redirect, err = srv.ShortenURL("asldkfjaslkdf")
+	if err == nil {
+		t.Error("Should throw error!")
+	}
+
+}
+
+func TestUtils(t *testing.T) {
+	if len(RandString(10)) != 10 {
+		t.Error("RandString is weird")
+	}
+	if RandString(3) == RandString(3) {
+		t.Error("RandString should be different")
+	}
+
+	if _, err := loadTemplates("", "index.html"); err != nil {
+		t.Error(err)
+	}
+}
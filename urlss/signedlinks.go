@@ -0,0 +1,51 @@
+package urlss
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/schollz/urlss/signed"
+)
+
+// tokenPrefix starts the path of signed links, /~token
+const tokenPrefix = "~"
+
+var (
+	errLinkInvalid = blockedError(errors.New("This link is invalid"))
+	errLinkExpired = expiredError(errors.New("This link has expired"))
+)
+
+// signLink returns the path-style target of a signed link to code
+func (s *Server) signLink(code string, expires time.Time) string {
+	return tokenPrefix + signed.Sign(s.secret, code, expires)
+}
+
+// resolveCode splits a path-style target into the short code and the
+// path and query appended to it, checking the token of signed links
+func (s *Server) resolveCode(target string) (code, extra string, viaToken bool, err error) {
+	code, extra = splitCode(target)
+	if !strings.HasPrefix(code, tokenPrefix) {
+		return
+	}
+	viaToken = true
+	code, _, err = signed.Verify(s.secret, strings.TrimPrefix(code, tokenPrefix), time.Now())
+	switch err {
+	case signed.ErrExpired:
+		err = errLinkExpired
+	case nil:
+	default:
+		err = errLinkInvalid
+	}
+	return
+}
+
+// derivedKey is a key for another use of the secret, so
+// tokens made for one use are useless for the others
+func (s *Server) derivedKey(use string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(use))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,130 @@
+package urlss
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignedLinks(t *testing.T) {
+	s := newTestServer(t, nil)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/api/links", strings.NewReader(`{"url":"https://files.example.com/report.pdf","signed":true,"expires_in":"1h"}`)))
+	var created struct {
+		Link apiLink
+		Path string
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if w.Code != 201 || !strings.HasPrefix(created.Path, "/~"+created.Link.Code+".") {
+		t.Fatalf("Got %d: %s", w.Code, w.Body.String())
+	}
+	if created.Link.URL != "" {
+		t.Errorf("Anonymous clients should not see where signed links go, got %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", created.Path, nil))
+	if w.Code != 302 || w.Header().Get("Location") != "https://files.example.com/report.pdf" {
+		t.Errorf("Got %d to %s", w.Code, w.Header().Get("Location"))
+	}
+	if w.Header().Get("Cache-Control") != "private, no-store" {
+		t.Errorf("Signed links should not be cached past their expiry, got %s", w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/"+created.Link.Code, nil))
+	if w.Code/100 == 3 {
+		t.Error("Signed links should not redirect without a token")
+	}
+
+	tampered := strings.Replace(created.Path, "/~"+created.Link.Code, "/~"+created.Link.Code+"x", 1)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", tampered, nil))
+	if w.Code/100 == 3 || !strings.Contains(w.Body.String(), errLinkInvalid.Error()) {
+		t.Errorf("Tampered link got %d", w.Code)
+	}
+
+	expired := "/" + s.signLink(created.Link.Code, time.Now().Add(-time.Minute))
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", expired, nil))
+	if w.Code/100 == 3 || !strings.Contains(w.Body.String(), errLinkExpired.Error()) {
+		t.Errorf("Expired link got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, apiRequest("POST", "/api/links/"+created.Link.Code+"/sign", strings.NewReader(`{"expires_in":"10m"}`)))
+	var token struct {
+		Path string
+	}
+	json.Unmarshal(w.Body.Bytes(), &token)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", token.Path, nil))
+	if w.Code != 302 {
+		t.Errorf("New token got %d", w.Code)
+	}
+}
+
+func TestAPIToken(t *testing.T) {
+	s := newTestServer(t, nil)
+	code, _ := s.AddLink(Link{Variants: []Variant{{"https://files.example.com/a", 1}}, Signed: true})
+	for _, r := range []struct{ method, path string }{
+		{"GET", "/api/links"},
+		{"GET", "/api/links/" + code},
+		{"PATCH", "/api/links/" + code},
+		{"DELETE", "/api/links/" + code},
+		{"POST", "/api/links/" + code + "/sign"},
+		{"POST", "/api/links/" + code + "/route"},
+		{"GET", "/api/links/" + code + "/schedule"},
+		{"GET", "/api/export"},
+		{"POST", "/api/import"},
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(r.method, r.path, strings.NewReader(`{"expires_in":"87600h"}`))
+		req.Header.Set("Authorization", "Bearer wrong")
+		s.ServeHTTP(w, req)
+		if w.Code != 401 {
+			t.Errorf("%s %s got %d without the token", r.method, r.path, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/api/links/"+code+"/stats", nil))
+	if w.Code != 200 || strings.Contains(w.Body.String(), "files.example.com") {
+		t.Errorf("Got %d: %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, apiRequest("GET", "/api/links/"+code+"/stats", nil))
+	if !strings.Contains(w.Body.String(), "files.example.com") {
+		t.Errorf("The token should see the variants, got %s", w.Body.String())
+	}
+
+	open := newTestServer(t, func(cfg *Config) { cfg.APIToken = "" })
+	w = httptest.NewRecorder()
+	open.ServeHTTP(w, httptest.NewRequest("GET", "/api/links", nil))
+	if w.Code != 403 {
+		t.Errorf("Got %d without an api_token set", w.Code)
+	}
+}
+
+func TestSignedProtectedLink(t *testing.T) {
+	s := newTestServer(t, nil)
+	code, _ := s.AddLink(Link{URL: "https://files.example.com/report.pdf", Signed: true, PasswordHash: hashPassword("hunter2")})
+	path := "/" + s.signLink(code, time.Now().Add(time.Hour))
+	req := httptest.NewRequest("POST", path, strings.NewReader("password=hunter2"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	cookies := w.Result().Cookies()
+	if w.Code != 302 || len(cookies) != 1 || !strings.HasPrefix(path, cookies[0].Path) {
+		t.Fatalf("The cookie should be sent back to %s, got %d %v", path, w.Code, cookies)
+	}
+	req = httptest.NewRequest("GET", path, nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if w.Code != 302 {
+		t.Errorf("The cookie should skip the prompt, got %d", w.Code)
+	}
+}
@@ -0,0 +1,175 @@
+package urlss
+
+import (
+	"sync"
+	"time"
+)
+
+// Stats are the click analytics of a short code
+type Stats struct {
+	Clicks    int64     `json:"clicks"`
+	LastClick time.Time `json:"last_click,omitempty"`
+	// Variants counts the clicks sent to each variant of a split link
+	Variants []int64 `json:"variants,omitempty"`
+	// Created is when the link was made, or in the shortener it was
+	// imported from. Older links and imports without a date have none.
+	Created time.Time `json:"created,omitempty"`
+}
+
+// add adds the clicks of other to stats
+func (stats *Stats) add(other Stats) {
+	stats.Clicks += other.Clicks
+	if other.LastClick.After(stats.LastClick) {
+		stats.LastClick = other.LastClick
+	}
+	for i, n := range other.Variants {
+		for len(stats.Variants) <= i {
+			stats.Variants = append(stats.Variants, 0)
+		}
+		stats.Variants[i] += n
+	}
+}
+
+// statsKey is where the stats of a code are stored, the slash
+// keeps it apart from codes and URLs
+func statsKey(code string) string {
+	return "stats/" + code
+}
+
+// Stats loads the stats of a code, with the clicks
+// that are not saved yet
+func (s *Server) Stats(code string) Stats {
+	stats := s.storedStats(code)
+	stats.add(s.clicks.get(code))
+	return stats
+}
+
+// storedStats loads the stats of a code as they are in the store
+func (s *Server) storedStats(code string) (stats Stats) {
+	s.store.Get(statsKey(code), &stats)
+	return
+}
+
+// clickCounts are the clicks of each code since they were last
+// saved, redirects count them in memory rather than saving the
+// store each time
+type clickCounts struct {
+	sync.Mutex
+	pending map[string]*Stats
+}
+
+// count counts a click of a code on a variant, which
+// is -1 for other redirects
+func (cc *clickCounts) count(code string, variant int) {
+	cc.Lock()
+	defer cc.Unlock()
+	if cc.pending == nil {
+		cc.pending = make(map[string]*Stats)
+	}
+	p, ok := cc.pending[code]
+	if !ok {
+		p = new(Stats)
+		cc.pending[code] = p
+	}
+	click := Stats{Clicks: 1, LastClick: time.Now().UTC()}
+	if variant >= 0 {
+		click.Variants = make([]int64, variant+1)
+		click.Variants[variant] = 1
+	}
+	p.add(click)
+}
+
+// get returns the clicks of a code that are not saved yet
+func (cc *clickCounts) get(code string) Stats {
+	cc.Lock()
+	defer cc.Unlock()
+	if p, ok := cc.pending[code]; ok {
+		return *p
+	}
+	return Stats{}
+}
+
+// take returns the clicks of a code and forgets them
+func (cc *clickCounts) take(code string) Stats {
+	cc.Lock()
+	defer cc.Unlock()
+	p, ok := cc.pending[code]
+	if !ok {
+		return Stats{}
+	}
+	delete(cc.pending, code)
+	return *p
+}
+
+// takeAll returns the clicks of every code and forgets them
+func (cc *clickCounts) takeAll() map[string]*Stats {
+	cc.Lock()
+	defer cc.Unlock()
+	pending := cc.pending
+	cc.pending = nil
+	return pending
+}
+
+// addAll counts the clicks of takeAll again
+func (cc *clickCounts) addAll(pending map[string]*Stats) {
+	cc.Lock()
+	defer cc.Unlock()
+	if cc.pending == nil {
+		cc.pending = make(map[string]*Stats)
+	}
+	for code, p := range pending {
+		if counted, ok := cc.pending[code]; ok {
+			p.add(*counted)
+		}
+		cc.pending[code] = p
+	}
+}
+
+// recordClick counts a redirect of a code to
+// a variant, which is -1 for other redirects
+func (s *Server) recordClick(code string, variant int) {
+	s.clicks.count(code, variant)
+}
+
+// flushClicks saves the clicks counted since the last flush,
+// they are counted again if they could not be saved
+func (s *Server) flushClicks() {
+	s.clicks.Lock()
+	n := len(s.clicks.pending)
+	s.clicks.Unlock()
+	if n == 0 {
+		return
+	}
+	var pending map[string]*Stats
+	err := s.update(func() error {
+		pending = s.clicks.takeAll()
+		for code, p := range pending {
+			if _, err := s.Lookup(code); err != nil {
+				// deleted since
+				continue
+			}
+			stats := s.storedStats(code)
+			stats.add(*p)
+			s.store.Set(statsKey(code), stats)
+		}
+		return nil
+	})
+	if err != nil {
+		s.log.Error("Could not save the clicks", "error", err)
+		s.clicks.addAll(pending)
+	}
+}
+
+// flushEvery saves the clicks every interval until Close
+func (s *Server) flushEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.flushClicks()
+		case <-s.stopFlush:
+			return
+		}
+	}
+}
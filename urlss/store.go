@@ -0,0 +1,188 @@
+package urlss
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/schollz/jsonstore"
+)
+
+// The data file is shared with the commands of urlss, which may
+// change it while a server runs. Changes are made with the file
+// locked, to the store as it is in the file, and saved at once.
+
+// fileState tells versions of the data file apart
+type fileState struct {
+	modified time.Time
+	size     int64
+}
+
+// statFile returns the version of the file at path, which
+// is zero if there is no file
+func statFile(path string) fileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{info.ModTime(), info.Size()}
+}
+
+// errClosed refuses changes after Close
+var errClosed = &statusError{http.StatusServiceUnavailable, errors.New("The server is shutting down")}
+
+// errSaveFailed is returned for changes that could not be saved,
+// the cause is logged
+var errSaveFailed = &statusError{http.StatusInternalServerError, errors.New("Could not save the links")}
+
+// update applies change to the store, as it is in the data file,
+// and saves it. The file stays locked meanwhile. Changes that fail
+// or could not be saved, with errSaveFailed, are undone.
+func (s *Server) update(change func() error) error {
+	s.fileMu.Lock()
+	defer s.fileMu.Unlock()
+	if s.closed {
+		return errClosed
+	}
+	unlock, err := lockFile(s.cfg.Data)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err = s.reload(); err != nil {
+		return err
+	}
+	before := s.snapshot()
+	if err = change(); err != nil {
+		s.restore(before)
+		return err
+	}
+	start := time.Now()
+	s.saveErr = s.save()
+	s.metrics.observeSave(time.Since(start), s.saveErr)
+	if s.saveErr != nil {
+		s.log.Error("Could not save the links", "file", s.cfg.Data, "error", s.saveErr)
+		s.restore(before)
+		return errSaveFailed
+	}
+	s.file = statFile(s.cfg.Data)
+	return nil
+}
+
+// snapshot copies the records of the store, which
+// are replaced rather than changed by Set
+func (s *Server) snapshot() map[string]json.RawMessage {
+	s.store.RLock()
+	defer s.store.RUnlock()
+	data := make(map[string]json.RawMessage, len(s.store.Data))
+	for key, value := range s.store.Data {
+		data[key] = value
+	}
+	return data
+}
+
+// restore puts back the records of a snapshot
+func (s *Server) restore(data map[string]json.RawMessage) {
+	s.store.Lock()
+	s.store.Data = data
+	s.store.Unlock()
+}
+
+// save writes the store to a temporary file and renames it over
+// the data file, which is never left half written. The lock keeps
+// other processes from saving at the same time.
+func (s *Server) save() error {
+	tmp := s.cfg.Data + ".tmp"
+	if strings.HasSuffix(s.cfg.Data, ".gz") {
+		// jsonstore gzips files named .gz
+		tmp = strings.TrimSuffix(s.cfg.Data, ".gz") + ".tmp.gz"
+	}
+	err := jsonstore.Save(s.store, tmp)
+	if err == nil {
+		// file systems may keep times coarser than saves come,
+		// a precise one tells this version from the last
+		now := time.Now()
+		err = os.Chtimes(tmp, now, now)
+	}
+	if err == nil {
+		err = os.Rename(tmp, s.cfg.Data)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// Close saves the clicks counted in memory, waits for the change
+// that is being saved and refuses any later one, the server is no
+// longer ready. It returns why the last change could not be saved,
+// if it could not.
+func (s *Server) Close() error {
+	s.stopOnce.Do(func() { close(s.stopFlush) })
+	s.flushClicks()
+	s.fileMu.Lock()
+	defer s.fileMu.Unlock()
+	s.closed = true
+	atomic.StoreInt32(&s.ready, 0)
+	return s.saveErr
+}
+
+// load reads the data file into the empty store of a new
+// server, which is ready once it did
+func (s *Server) load() {
+	s.fileMu.Lock()
+	defer s.fileMu.Unlock()
+	unlock, err := lockFile(s.cfg.Data)
+	if err == nil {
+		err = s.reload()
+		unlock()
+	}
+	if err != nil {
+		s.loadErr = err
+		s.log.Error("Could not load the links", "file", s.cfg.Data, "error", err)
+		return
+	}
+	if !s.closed {
+		atomic.StoreInt32(&s.ready, 1)
+	}
+}
+
+// refresh reloads the store if another process changed the data file
+func (s *Server) refresh() {
+	s.fileMu.Lock()
+	defer s.fileMu.Unlock()
+	if state := statFile(s.cfg.Data); state == s.file || state == (fileState{}) {
+		return
+	}
+	unlock, err := lockFile(s.cfg.Data)
+	if err != nil {
+		s.log.Error("Could not lock the links", "file", s.cfg.Data, "error", err)
+		return
+	}
+	defer unlock()
+	if err = s.reload(); err != nil {
+		s.log.Error("Could not reload the links", "file", s.cfg.Data, "error", err)
+	}
+}
+
+// reload replaces the store with the data file if it changed
+// since the store was synced with it, the file is locked
+func (s *Server) reload() error {
+	state := statFile(s.cfg.Data)
+	if state == s.file || state == (fileState{}) {
+		return nil
+	}
+	fresh, err := jsonstore.Open(s.cfg.Data)
+	if err != nil {
+		return err
+	}
+	s.store.Lock()
+	s.store.Data = fresh.Data
+	s.store.Unlock()
+	s.file = state
+	return nil
+}
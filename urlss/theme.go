@@ -0,0 +1,78 @@
+package urlss
+
+import (
+	"errors"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin/render"
+)
+
+// templateNames are the pages the server renders
+var templateNames = []string{"index.html", "password.html", "stats.html"}
+
+// readTemplate parses a template from dir, or from the
+// built-in assets when it is not overridden
+func readTemplate(dir, name string) (*template.Template, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if dir != "" {
+		data, err = ioutil.ReadFile(filepath.Join(dir, name))
+	}
+	if dir == "" || os.IsNotExist(err) {
+		data, err = Asset("templates/" + name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return template.New(name).Funcs(templateFuncs).Parse(string(data))
+}
+
+// checkDir makes sure a directory flag names a directory
+func checkDir(flagName, dir string) error {
+	if dir == "" {
+		return nil
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errors.New("-" + flagName + " " + dir + " is not a directory")
+	}
+	return nil
+}
+
+// devRender reads the templates in dir on each page, so
+// edits show up on reload without restarting
+type devRender struct {
+	dir string
+}
+
+func (d devRender) Instance(name string, data interface{}) render.Render {
+	tmpl, err := readTemplate(d.dir, name)
+	if err != nil {
+		return templateError{err}
+	}
+	return render.HTML{Template: tmpl, Data: data}
+}
+
+// templateError shows a broken template instead of the page
+type templateError struct {
+	err error
+}
+
+func (t templateError) Render(w http.ResponseWriter) error {
+	t.WriteContentType(w)
+	_, err := w.Write([]byte(t.err.Error()))
+	return err
+}
+
+func (t templateError) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+}
@@ -0,0 +1,68 @@
+package urlss
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTemplateOverrides(t *testing.T) {
+	dir, err := ioutil.TempDir("", "urlss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	index := filepath.Join(dir, "index.html")
+	ioutil.WriteFile(index, []byte("<h1>Branded</h1>"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "logo.svg"), []byte("<svg/>"), 0644)
+	s := newTestServer(t, func(cfg *Config) {
+		cfg.Templates, cfg.Static = dir, dir
+	})
+
+	get := func(path string) string {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Body.String()
+	}
+	if body := get("/"); body != "<h1>Branded</h1>" {
+		t.Errorf("Got %s", body)
+	}
+	if body := get("/static/logo.svg"); body != "<svg/>" {
+		t.Errorf("Got %s", body)
+	}
+
+	// templates that are not overridden are built in
+	tmpl, err := readTemplate(dir, "password.html")
+	if err != nil || tmpl == nil {
+		t.Error(err)
+	}
+
+	r := newTestServer(t, func(cfg *Config) {
+		cfg.Templates, cfg.Dev = dir, true
+	})
+	ioutil.WriteFile(index, []byte("<h1>Edited</h1>"), 0644)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Body.String() != "<h1>Edited</h1>" {
+		t.Errorf("Got %s", w.Body.String())
+	}
+	ioutil.WriteFile(index, []byte("{{ .broken"), 0644)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if !strings.Contains(w.Body.String(), "index.html") {
+		t.Errorf("Template errors should be shown, got %s", w.Body.String())
+	}
+
+	if checkDir("templates", index) == nil {
+		t.Error("Files are not directories")
+	}
+
+	cfg := DefaultConfig()
+	cfg.Templates = dir
+	if _, err = NewServer(cfg, nil, nil); err == nil || !strings.Contains(err.Error(), "templates: template: index.html") {
+		t.Errorf("A broken template should be a configuration error, got %v", err)
+	}
+}
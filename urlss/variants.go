@@ -0,0 +1,147 @@
+package urlss
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Variant is one of the weighted destinations of a split link
+type Variant struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+var rotations = map[string]bool{"": true, "random": true, "round-robin": true}
+
+// stickyDays is how long a visitor keeps their variant
+const stickyDays = 30
+
+// validateVariants checks the variants of a link
+// and makes their URLs canonical
+func (l *Link) validateVariants(p urlPolicy) (err error) {
+	if len(l.Variants) == 0 {
+		l.Variants = nil
+	}
+	if !rotations[l.Rotation] {
+		return errors.New("Unknown rotation " + l.Rotation)
+	}
+	if l.Variants == nil {
+		if l.Rotation != "" || l.Sticky {
+			return errors.New("Rotation needs variants")
+		}
+		return nil
+	}
+	total := 0
+	for i := range l.Variants {
+		if l.Variants[i].Weight < 0 {
+			return errors.New("Weights can not be negative")
+		}
+		total += l.Variants[i].Weight
+		if l.Variants[i].URL, err = p.canonicalURL(l.Variants[i].URL); err != nil {
+			return
+		}
+	}
+	if total == 0 {
+		return errors.New("Variants need a positive weight")
+	}
+	return nil
+}
+
+// rekeyVariants keeps the clicks of each variant when the variants
+// of a link change, by their URL. New variants start without clicks.
+func (stats *Stats) rekeyVariants(old, variants []Variant) {
+	if len(stats.Variants) == 0 {
+		return
+	}
+	counted := stats.Variants
+	stats.Variants = nil
+	used := make([]bool, len(old))
+	for i, v := range variants {
+		for j := range old {
+			if !used[j] && j < len(counted) && old[j].URL == v.URL {
+				used[j] = true
+				for len(stats.Variants) <= i {
+					stats.Variants = append(stats.Variants, 0)
+				}
+				stats.Variants[i] = counted[j]
+				break
+			}
+		}
+	}
+}
+
+// rotation counts the requests to each split link since startup,
+// and draws the random variants
+type rotation struct {
+	sync.Mutex
+	next   map[string]int
+	random *rand.Rand
+}
+
+func newRotation() *rotation {
+	return &rotation{
+		next:   make(map[string]int),
+		random: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// variantKey names a variant in sticky cookies by its URL, so
+// visitors keep it when the variants are reordered
+func variantKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:8])
+}
+
+// pickVariant chooses the variant for a request, or -1 if the link
+// has none. Sticky links reuse the variant in the visitor's cookie
+// and remember a new one with w for the path of the link.
+func (l Link) pickVariant(code, path string, w http.ResponseWriter, r *http.Request, rot *rotation) int {
+	if len(l.Variants) == 0 {
+		return -1
+	}
+	cookie := "urlss_" + code
+	if l.Sticky && r != nil {
+		if c, err := r.Cookie(cookie); err == nil {
+			for i, v := range l.Variants {
+				if v.Weight > 0 && variantKey(v.URL) == c.Value {
+					return i
+				}
+			}
+		}
+	}
+	total := 0
+	for _, v := range l.Variants {
+		total += v.Weight
+	}
+	rot.Lock()
+	n := 0
+	if l.Rotation == "round-robin" {
+		n = rot.next[code] % total
+		rot.next[code] = n + 1
+	} else {
+		n = rot.random.Intn(total)
+	}
+	rot.Unlock()
+	variant := 0
+	for i, v := range l.Variants {
+		if n < v.Weight {
+			variant = i
+			break
+		}
+		n -= v.Weight
+	}
+	if l.Sticky && w != nil {
+		http.SetCookie(w, &http.Cookie{
+			Name:   cookie,
+			Value:  variantKey(l.Variants[variant].URL),
+			Path:   path,
+			MaxAge: stickyDays * 24 * 60 * 60,
+		})
+	}
+	return variant
+}
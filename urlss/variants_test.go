@@ -0,0 +1,105 @@
+package urlss
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVariants(t *testing.T) {
+	s := newTestServer(t, nil)
+	code, err := s.AddLink(Link{
+		Variants: []Variant{
+			{URL: "https://example.com/a", Weight: 3},
+			{URL: "https://example.com/b", Weight: 1},
+		},
+		Rotation: "round-robin",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]int{}
+	for i := 0; i < 8; i++ {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/"+code, nil))
+		counts[w.Header().Get("Location")]++
+	}
+	if counts["https://example.com/a"] != 6 || counts["https://example.com/b"] != 2 {
+		t.Errorf("Got %v", counts)
+	}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/api/links/"+code+"/stats", nil))
+	var stats struct {
+		Variants []VariantStats
+	}
+	json.Unmarshal(w.Body.Bytes(), &stats)
+	if len(stats.Variants) != 2 || stats.Variants[0].Clicks != 6 || stats.Variants[1].Clicks != 2 {
+		t.Errorf("Got %s", w.Body.String())
+	}
+
+	// clicks stay with their URL when the variants change
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, apiRequest("PATCH", "/api/links/"+code, strings.NewReader(`{"variants":[{"url":"https://example.com/b","weight":1},{"url":"https://example.com/c","weight":1},{"url":"https://example.com/a","weight":1}]}`)))
+	if w.Code != 200 {
+		t.Fatalf("Got %d: %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/api/links/"+code+"/stats", nil))
+	json.Unmarshal(w.Body.Bytes(), &stats)
+	if len(stats.Variants) != 3 || stats.Variants[0].Clicks != 2 || stats.Variants[1].Clicks != 0 || stats.Variants[2].Clicks != 6 {
+		t.Errorf("Got %s", w.Body.String())
+	}
+
+	if _, err := s.AddLink(Link{Variants: []Variant{{URL: "https://example.com/a"}}}); err == nil {
+		t.Error("Should refuse variants without weight")
+	}
+}
+
+func TestStickyVariant(t *testing.T) {
+	s := newTestServer(t, nil)
+	code, err := s.AddLink(Link{
+		Variants: []Variant{
+			{URL: "https://example.com/x", Weight: 1},
+			{URL: "https://example.com/y", Weight: 1},
+		},
+		Sticky: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/"+code, nil))
+	first := w.Header().Get("Location")
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("Got cookies %v", cookies)
+	}
+	for i := 0; i < 10; i++ {
+		req := httptest.NewRequest("GET", "/"+code, nil)
+		req.AddCookie(cookies[0])
+		w = httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if w.Header().Get("Location") != first {
+			t.Errorf("Sticky visitor moved from %s to %s", first, w.Header().Get("Location"))
+		}
+	}
+	if w.Code != 302 || w.Header().Get("Cache-Control") != "private, no-store" {
+		t.Errorf("Split links should not be cached, got %d %s", w.Code, w.Header())
+	}
+
+	// reordering the variants keeps the visitor on theirs
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, apiRequest("PATCH", "/api/links/"+code, strings.NewReader(`{"variants":[{"url":"https://example.com/y","weight":1},{"url":"https://example.com/x","weight":1}]}`)))
+	if w.Code != 200 {
+		t.Fatalf("Got %d: %s", w.Code, w.Body.String())
+	}
+	req := httptest.NewRequest("GET", "/"+code, nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if w.Header().Get("Location") != first {
+		t.Errorf("Sticky visitor moved from %s to %s after a reorder", first, w.Header().Get("Location"))
+	}
+}